@@ -2,45 +2,529 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"logagg/internal/aggregator"
+	"logagg/internal/checkpoint"
+	"logagg/internal/config"
 	"logagg/internal/filter"
+	"logagg/internal/level"
+	"logagg/internal/logline"
+	"logagg/internal/multiline"
+	"logagg/internal/output"
+	"logagg/internal/parser"
+	"logagg/internal/query"
 	"logagg/internal/reader"
+	"logagg/internal/source"
+	"logagg/internal/timestamp"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var files []string
-var filterParam string
+var includeFiles []string
+var excludeFiles []string
+var rescanInterval time.Duration
+var filterParams []string
+var excludeParams []string
+var matchMode string
+var ignoreCase bool
+var queryParam string
+var outputFormat string
+var logFormats []string
+var grokPatterns []string
+var timestampFormats []string
+var timezones []string
+var sinceParam string
+var untilParam string
+var levelParam string
+var colorParam string
+var templateParam string
 var tail bool
+var watchMode string
+var sortByTime bool
+var sortWindow time.Duration
+var multilineRule multiline.Rule
+var checkpointFile string
+var checkpointInterval time.Duration
+var strict bool
+var maxLineSize int
+var longLinePolicy string
+var configFile string
+var watchConfig bool
+
+// configured records the flags set from the configuration file, so errors
+// in their values can point at the key that set them.
+var configured = map[string]config.Pos{}
 
 var rootCmd = &cobra.Command{
-	Use:   "logagg",
+	Use:   "logagg [arquivos...]",
 	Short: "Monitorador de logs",
 	Run: func(cmd *cobra.Command, args []string) {
-		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 		defer cancel()
-		channels := make([]<-chan string, 0, len(files))
+		// Ctrl+C, SIGTERM and --strict only stop the readers. The stages
+		// after them run until their input closes, so the lines already
+		// read are written, and counted in the checkpoint, before logagg
+		// exits. A second signal exits at once.
+		drain := context.Background()
+		context.AfterFunc(ctx, cancel)
+
+		// Flags given on the command line win over the configuration file,
+		// so the ones set there are noted before it is applied.
+		onCommandLine := make(map[string]bool)
+		cmd.Flags().Visit(func(f *pflag.Flag) { onCommandLine[f.Name] = true })
+		var cfg config.Config
+		if configFile != "" {
+			c, err := config.Load(configFile)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Erro: ", err)
+				os.Exit(1)
+			}
+			if err := applyDefaults(cmd.Flags(), c.Defaults, onCommandLine); err != nil {
+				fmt.Fprintln(os.Stderr, "Erro: ", err)
+				os.Exit(1)
+			}
+			cfg = *c
+		}
+
+		watch, err := reader.ParseWatchMode(watchMode)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Erro: ", flagError("watch", err))
+			os.Exit(1)
+		}
+
+		policy, err := reader.ParseLongLinePolicy(longLinePolicy)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Erro: ", flagError("long-lines", err))
+			os.Exit(1)
+		}
+		var longLines atomic.Int64
+		limit := reader.LineLimit{Max: maxLineSize, Policy: policy, Count: &longLines}
+
+		if matchMode != "all" && matchMode != "any" {
+			fmt.Fprintln(os.Stderr, "Erro: ", flagError("match", fmt.Errorf("modo de combinação inválido %q: use all ou any", matchMode)))
+			os.Exit(1)
+		}
+		matcher, err := filter.Compile(filter.Options{
+			Include:    filterParams,
+			Exclude:    excludeParams,
+			Any:        matchMode == "any",
+			IgnoreCase: ignoreCase,
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Erro: ", err)
+			os.Exit(1)
+		}
+
+		var q query.Node
+		if queryParam != "" {
+			q, err = query.Parse(queryParam)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Erro: ", flagError("query", err))
+				var serr *query.SyntaxError
+				if errors.As(err, &serr) {
+					fmt.Fprintln(os.Stderr, serr.Context())
+				}
+				os.Exit(1)
+			}
+		}
 
-		for _, f := range files {
+		for _, f := range grokPatterns {
+			if err := parser.Patterns.LoadFile(f); err != nil {
+				fmt.Fprintln(os.Stderr, "Erro: ", flagError("grok-patterns", err))
+				os.Exit(1)
+			}
+		}
+		// Formats of the configuration file are checked once the pattern
+		// files, which may be set in it, are loaded.
+		if err := cfg.CheckFormats(); err != nil {
+			fmt.Fprintln(os.Stderr, "Erro: ", err)
+			os.Exit(1)
+		}
+		formatOf, err := perSource(logFormats, "auto", func(v string) error {
+			_, err := parser.New(v)
+			return err
+		}, func(v string) bool {
+			// A log_format or grok expression may contain '=' of its own.
+			return strings.HasPrefix(v, parser.NginxPrefix) || strings.HasPrefix(v, parser.GrokPrefix)
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Erro: ", flagError("format", err))
+			os.Exit(1)
+		}
+		layoutOf, err := perSource(timestampFormats, "", func(v string) error {
+			_, err := timestamp.ParseLayout(v)
+			return err
+		}, nil)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Erro: ", flagError("timestamp-format", err))
+			os.Exit(1)
+		}
+		zoneOf, err := perSource(timezones, "", func(v string) error {
+			if _, err := time.LoadLocation(v); err != nil {
+				return fmt.Errorf("fuso horário inválido %q: %w", v, err)
+			}
+			return nil
+		}, nil)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Erro: ", flagError("timezone", err))
+			os.Exit(1)
+		}
 
+		formatter, err := output.New(outputFormat)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Erro: ", flagError("output", err))
+			os.Exit(1)
+		}
+		colorMode, err := output.ParseColorMode(colorParam)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Erro: ", flagError("color", err))
+			os.Exit(1)
+		}
+		// Only the text format is colorized; JSON stays machine readable.
+		colors := outputFormat == "text" && colorMode.Enabled(os.Stdout)
+		switch {
+		case templateParam != "":
+			if outputFormat != "text" {
+				fmt.Fprintln(os.Stderr, "Erro: ", fmt.Errorf("--template não pode ser usado com --output %s", outputFormat))
+				os.Exit(1)
+			}
+			formatter, err = output.NewTemplate(templateParam, colors)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Erro: ", flagError("template", err))
+				os.Exit(1)
+			}
+		case colors:
+			formatter = output.Color{Highlight: matcher.Highlights}
+		}
+
+		var joiner *multiline.Joiner
+		if multilineRule.Enabled() {
+			joiner, err = multiline.Compile(multilineRule)
+			if err != nil {
+				flag := "multiline-start"
+				if _, serr := regexp.Compile(multilineRule.Start); serr == nil {
+					flag = "multiline-continue"
+				}
+				fmt.Fprintln(os.Stderr, "Erro: ", flagError(flag, err))
+				os.Exit(1)
+			}
+		}
+
+		var store *checkpoint.Store
+		if checkpointFile != "" {
+			store, err = checkpoint.Open(checkpointFile)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Erro: ", err)
+				os.Exit(1)
+			}
+		}
+		var handoff *checkpoint.Store
+		if tail && configFile != "" {
+			// Readers restarted by a reload resume after the last line
+			// the previous ones sent instead of reading their files again.
+			handoff = checkpoint.NewMemory()
+		}
+
+		var since, until time.Time
+		now := time.Now()
+		if sinceParam != "" {
+			if since, err = timestamp.ParseBound(sinceParam, now); err != nil {
+				fmt.Fprintln(os.Stderr, "Erro: ", flagError("since", err))
+				os.Exit(1)
+			}
+		}
+		if untilParam != "" {
+			if until, err = timestamp.ParseBound(untilParam, now); err != nil {
+				fmt.Fprintln(os.Stderr, "Erro: ", flagError("until", err))
+				os.Exit(1)
+			}
+		}
+		if !since.IsZero() && !until.IsZero() && !since.Before(until) {
+			fmt.Fprintln(os.Stderr, "Erro: ", fmt.Errorf("--since (%s) deve ser anterior a --until (%s)", since.Format(time.RFC3339), until.Format(time.RFC3339)))
+			os.Exit(1)
+		}
+
+		var minLevel level.Level
+		if levelParam != "" {
+			if minLevel, err = level.ParseThreshold(levelParam); err != nil {
+				fmt.Fprintln(os.Stderr, "Erro: ", flagError("level", err))
+				os.Exit(1)
+			}
+		}
+
+		// A failing source only stops its own reader. In strict mode the
+		// first failure stops everything and the exit status reports it.
+		var failed atomic.Bool
+		sourceFailed := func() {
+			if strict {
+				failed.Store(true)
+				cancel()
+			}
+		}
+
+		events := make(chan reader.Event)
+		stopEvents := make(chan struct{})
+		eventsDone := make(chan struct{})
+
+		go func() {
+			defer close(eventsDone)
+			for {
+				select {
+				case ev := <-events:
+					if ev.Kind == reader.EventError {
+						fmt.Fprintf(os.Stderr, "Erro: [%s] - %v\n", filepath.Base(ev.Source), ev.Err)
+						sourceFailed()
+						continue
+					}
+					fmt.Fprintf(os.Stderr, "[%s] - arquivo %s\n", filepath.Base(ev.Source), ev.Kind)
+				case <-stopEvents:
+					return
+				}
+			}
+		}()
+
+		// Positional arguments are sources too, so a shell glob such as
+		// --files app.log* works even though it expands to several words.
+		var specs []source.Spec
+		for _, p := range append(files, args...) {
+			specs = append(specs, source.Spec{Pattern: p, Include: includeFiles, Exclude: excludeFiles})
+		}
+		discoverer := source.NewDiscoverer(specs)
+		discoverer.Skipped = func(path, of string) {
+			fmt.Fprintf(os.Stderr, "Aviso: %s ignorado, cópia rotacionada de %s\n", path, of)
+		}
+
+		// Sources of the configuration file bring settings and a context
+		// of their own, which end with ctx.
+		set := newSourceSet(ctx, discoverer, ignoreCase)
+		for _, cs := range cfg.Sources {
+			set.start(cs)
+		}
+		// A setting of the file's source applies unless the flag was given
+		// on the command line.
+		own := func(flag, value, def string) string {
+			if value != "" && !onCommandLine[flag] {
+				return value
+			}
+			return def
+		}
+
+		open := func(f string) (<-chan logline.Line, bool) {
+			ss, ok := set.of(f)
+			if !ok {
+				// Found just before its source was removed.
+				return nil, false
+			}
+			named := ss != nil
+			if !named {
+				ss = &sourceSettings{ctx: ctx}
+			}
 			if err := reader.ValidateFile(f); err != nil {
-				fmt.Println("Erro: ", err)
-				continue
+				fmt.Fprintln(os.Stderr, "Erro: ", err)
+				sourceFailed()
+				return nil, false
+			}
+			format := own("format", ss.Format, formatOf(f))
+			// Each source gets its own parser and detector, so format and
+			// layout detection are made per file.
+			detector := func() *timestamp.Detector {
+				d := &timestamp.Detector{}
+				if v := own("timestamp-format", ss.TimestampFormat, layoutOf(f)); v != "" {
+					d.Layout, _ = timestamp.ParseLayout(v)
+				}
+				if v := own("timezone", ss.Timezone, zoneOf(f)); v != "" {
+					d.Location, _ = time.LoadLocation(v)
+				}
+				return d
+			}
+
+			opts := reader.Options{Tail: tail, Watch: watch, Events: events, Checkpoints: store, Resume: handoff, Limit: limit, Since: since, Label: ss.Label}
+			if !since.IsZero() {
+				// The search for --since probes lines out of order, so it
+				// uses a parser and detector of its own.
+				probe, _ := parser.New(format)
+				d := detector()
+				opts.TimeOf = func(raw string) (time.Time, bool) {
+					l := logline.Line{Raw: raw, Ingested: now}
+					probe.Parse(&l)
+					return d.Time(l)
+				}
+			}
+			ch := reader.Read(ss.ctx, f, opts)
+			j := joiner
+			if ss.joiner != nil && !multilineOnCommandLine(onCommandLine) {
+				j = ss.joiner
+			}
+			if j != nil {
+				// Events are assembled per file, before lines of
+				// different sources are mixed.
+				ch = j.Join(drain, ch)
+			}
+			p, _ := parser.New(format)
+			ch = parser.Run(drain, ch, p)
+			ch = timestamp.Stamp(drain, ch, detector())
+			if !since.IsZero() || !until.IsZero() {
+				// Applied per file, so lines without a time follow the
+				// line before them in the same file.
+				ch = filter.Between(ch, since, until)
+			}
+			ch = level.Stamp(drain, ch)
+			if minLevel != level.Unknown {
+				ch = filter.AtLeast(ch, minLevel)
+			}
+			if ss.matcher != nil {
+				ch = filter.FilterFunc(ch, func(l logline.Line) bool {
+					return ss.matcher.Match(l.Raw)
+				})
+			}
+			if named {
+				// A reload stopping the source waits for its lines to
+				// reach the aggregator.
+				ch = ss.track(ch)
+			}
+			return ch, true
+		}
+
+		initial, err := discoverer.Scan()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Erro: ", err)
+			sourceFailed()
+		}
+
+		channels := make([]<-chan logline.Line, 0, len(initial))
+		for _, f := range initial {
+			if ch, ok := open(f); ok {
+				channels = append(channels, ch)
 			}
+		}
+
+		var lines <-chan logline.Line
+		if sortByTime && !tail {
+			lines = aggregator.Merge(drain, lineTime, channels...)
+		} else {
+			sources := make(chan (<-chan logline.Line), len(channels))
+			for _, ch := range channels {
+				sources <- ch
+			}
+
+			if tail {
+				// The configuration file is reloaded on SIGHUP and, with
+				// --watch-config, when it changes. Only its sources are
+				// reloaded: unchanged ones keep their readers, changed
+				// and removed ones are stopped once the lines they read
+				// are delivered, and new or changed ones are picked up by
+				// the next rescan.
+				var hup chan os.Signal
+				var changed <-chan struct{}
+				if configFile != "" {
+					hup = make(chan os.Signal, 1)
+					signal.Notify(hup, syscall.SIGHUP)
+					defer signal.Stop(hup)
+					if watchConfig {
+						changed = watchFile(ctx, configFile, rescanInterval)
+					}
+				}
+				reload := func() {
+					c, err := config.Load(configFile)
+					if err == nil {
+						err = c.CheckFormats()
+					}
+					if err != nil {
+						fmt.Fprintln(os.Stderr, "Erro ao recarregar configuração: ", err)
+						return
+					}
+					added, updated, removed := set.reload(c.Sources)
+					fmt.Fprintf(os.Stderr, "Configuração recarregada: %d fontes adicionadas, %d alteradas, %d removidas\n", added, updated, removed)
+				}
 
-			ch := reader.ReadLines(ctx, f, tail)
-			channels = append(channels, ch)
+				// Files matching the sources that show up later are
+				// followed as well. Reloads happen in the same goroutine
+				// that opens them, so the running sources need no lock.
+				go func() {
+					defer close(sources)
+					found := discoverer.Watch(ctx, rescanInterval)
+					for {
+						select {
+						case f, ok := <-found:
+							if !ok {
+								return
+							}
+							ch, ok := open(f)
+							if !ok {
+								continue
+							}
+							select {
+							case sources <- ch:
+							case <-ctx.Done():
+								return
+							}
+						case <-hup:
+							reload()
+						case <-changed:
+							reload()
+						case <-ctx.Done():
+							return
+						}
+					}
+				}()
+			} else {
+				close(sources)
+			}
 
+			if sortByTime {
+				lines = aggregator.MergeWindowStream(drain, lineTime, sortWindow, sources)
+			} else {
+				lines = aggregator.AggregateStream(drain, sources)
+			}
 		}
+		result := filter.FilterFunc(lines, func(l logline.Line) bool {
+			return matcher.Match(l.Raw) && (q == nil || q.Eval(l))
+		})
 
-		lines := aggregator.Aggregate(ctx, channels...)
-		result := filter.Filter(lines, filterParam)
+		// The store is flushed periodically and once more after the
+		// pipeline has drained.
+		stopStore := func() {}
+		if store != nil {
+			storeCtx, cancelStore := context.WithCancel(context.Background())
+			stored := make(chan struct{})
+			go func() {
+				defer close(stored)
+				store.Run(storeCtx, checkpointInterval, func(err error) {
+					fmt.Fprintln(os.Stderr, "Erro ao gravar checkpoint: ", err)
+				})
+			}()
+			stopStore = func() {
+				cancelStore()
+				<-stored
+			}
+		}
 
-		for l := range result {
-			fmt.Println(l)
+		err = output.Write(os.Stdout, result, formatter)
+		stopStore()
+		// Every reader has returned by now, so the events they sent have
+		// been received; wait for the last one to be handled.
+		close(stopEvents)
+		<-eventsDone
+		if n := longLines.Load(); n > 0 {
+			fmt.Fprintf(os.Stderr, "Aviso: %d linhas maiores que %d bytes (%s)\n", n, maxLineSize, policy)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Erro: ", err)
+			os.Exit(1)
+		}
+		if failed.Load() {
+			os.Exit(1)
 		}
 
 	},
@@ -48,15 +532,164 @@ var rootCmd = &cobra.Command{
 
 func init() {
 
-	rootCmd.Flags().StringSliceVarP(&files, "files", "f", []string{}, "Arquivos para monitorar")
-	rootCmd.Flags().StringVarP(&filterParam, "filter", "F", "", "Filtar o retorno do log por palavra")
+	rootCmd.Flags().StringVar(&configFile, "config", "", "Arquivo de configuração YAML com fontes nomeadas e valores padrão das flags")
+	rootCmd.Flags().BoolVar(&watchConfig, "watch-config", false, "No modo tail, recarrega as fontes do arquivo de configuração quando ele muda (também recarregado com SIGHUP)")
+	rootCmd.Flags().StringSliceVarP(&files, "files", "f", []string{}, "Arquivos, padrões glob (**/*.log) ou diretórios para monitorar")
+	rootCmd.Flags().StringSliceVar(&includeFiles, "include-files", nil, "Nos diretórios e globs, lê apenas arquivos cujo nome casa com estes padrões")
+	rootCmd.Flags().StringSliceVar(&excludeFiles, "exclude-files", nil, "Nos diretórios e globs, ignora arquivos cujo nome casa com estes padrões")
+	rootCmd.Flags().StringArrayVarP(&filterParams, "filter", "F", nil, "Filtra o retorno do log por expressão regular (pode ser repetido)")
+	rootCmd.Flags().StringArrayVarP(&excludeParams, "exclude", "x", nil, "Descarta as linhas que casam com a expressão regular (pode ser repetido)")
+	rootCmd.Flags().StringVar(&matchMode, "match", "all", "Combinação de vários --filter: all (E) ou any (OU)")
+	rootCmd.Flags().BoolVarP(&ignoreCase, "ignore-case", "i", false, "Ignora maiúsculas e minúsculas nos filtros")
+	rootCmd.Flags().StringVarP(&queryParam, "query", "q", "", `Consulta booleana, ex.: 'source:app.log AND (msg~"timeout" OR NOT retry)'`)
+	rootCmd.Flags().StringArrayVar(&logFormats, "format", nil, "Formato das linhas de log ("+strings.Join(parser.Names(), ", ")+"), para todos os arquivos ou padrão=formato (pode ser repetido; padrão auto)")
+	rootCmd.Flags().StringSliceVar(&grokPatterns, "grok-patterns", nil, "Arquivos com padrões grok adicionais, uma linha \"NOME padrão\" cada")
+	rootCmd.Flags().StringVar(&levelParam, "level", "", "Mostra apenas linhas deste nível ou mais graves: trace, debug, info, warn, error ou fatal")
+	rootCmd.Flags().StringVar(&sinceParam, "since", "", `Mostra apenas linhas a partir deste momento: duração (15m, 2h, 7d) ou data ("2024-01-15 10:00")`)
+	rootCmd.Flags().StringVar(&untilParam, "until", "", `Mostra apenas linhas anteriores a este momento: duração (15m, 2h, 7d) ou data ("2024-01-15 10:00")`)
+	rootCmd.Flags().StringArrayVar(&timestampFormats, "timestamp-format", nil, "Formato dos timestamps (rfc3339, datetime, slash, apache, syslog, epoch, epoch_ms ou layout Go), para todos os arquivos ou padrão=formato; padrão: detectado por arquivo")
+	rootCmd.Flags().StringArrayVar(&timezones, "timezone", nil, "Fuso horário dos timestamps sem fuso (ex.: America/Sao_Paulo, UTC), para todos os arquivos ou padrão=fuso; padrão: local")
+	rootCmd.Flags().StringVar(&templateParam, "template", "", `Formata cada linha com um template Go, por exemplo '{{.Time.Format "15:04:05"}} {{.Source}} {{.Message}}'`)
+	rootCmd.Flags().StringVar(&colorParam, "color", "auto", "Colore a saída de texto: always, never ou auto (quando a saída é um terminal e NO_COLOR não está definida)")
+	rootCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Formato de saída: "+strings.Join(output.Names(), ", "))
 	rootCmd.Flags().BoolVarP(&tail, "tail", "t", false, "Aguarda novas linhas no arquivo de log")
+	rootCmd.Flags().DurationVar(&rescanInterval, "rescan", 2*time.Second, "Intervalo para procurar novos arquivos no modo tail")
+	rootCmd.Flags().StringVar(&watchMode, "watch", string(reader.WatchNotify), "Como o modo tail detecta mudanças: fsnotify ou poll")
+	rootCmd.Flags().StringVar(&multilineRule.Start, "multiline-start", "", "Expressão regular que marca a primeira linha de um evento de várias linhas")
+	rootCmd.Flags().BoolVar(&multilineRule.StartTimestamp, "multiline-timestamp", false, "Linhas que começam com timestamp iniciam um novo evento")
+	rootCmd.Flags().StringVar(&multilineRule.Continue, "multiline-continue", "", "Expressão regular das linhas que continuam o evento anterior")
+	rootCmd.Flags().IntVar(&multilineRule.MaxLines, "multiline-max-lines", 500, "Máximo de linhas por evento")
+	rootCmd.Flags().IntVar(&multilineRule.MaxBytes, "multiline-max-bytes", 1<<20, "Máximo de bytes por evento")
+	rootCmd.Flags().DurationVar(&multilineRule.Timeout, "multiline-timeout", time.Second, "Tempo sem novas linhas após o qual um evento pendente é emitido")
+	rootCmd.Flags().StringVar(&checkpointFile, "checkpoint", "", "Arquivo onde salvar a posição de leitura para retomar após reiniciar")
+	rootCmd.Flags().DurationVar(&checkpointInterval, "checkpoint-interval", 5*time.Second, "Intervalo entre gravações do arquivo de checkpoint")
+	rootCmd.Flags().IntVar(&maxLineSize, "max-line-size", reader.DefaultMaxLineSize, "Tamanho máximo de uma linha em bytes")
+	rootCmd.Flags().StringVar(&longLinePolicy, "long-lines", string(reader.LongLineTruncate), "O que fazer com linhas maiores que --max-line-size: truncate, split ou skip")
+	rootCmd.Flags().BoolVar(&strict, "strict", false, "Encerra com código de saída diferente de zero no primeiro erro de um arquivo")
+	rootCmd.Flags().BoolVar(&sortByTime, "sort-by-time", false, "Ordena as linhas de todos os arquivos pelo timestamp")
+	rootCmd.Flags().DurationVar(&sortWindow, "sort-window", 2*time.Second, "Atraso máximo aceito ao ordenar por timestamp no modo tail")
+
+}
+
+// perSource reads flag values that apply to every source ("value") or to
+// the sources matching a pattern ("pattern=value"), by file name or, when
+// the pattern has a directory, by path. The first matching pattern wins and
+// def applies to the other sources. check validates each value; whole, when
+// set, tells values that may contain '=' of their own.
+func perSource(values []string, def string, check func(string) error, whole func(string) bool) (func(path string) string, error) {
+	type rule struct{ pattern, value string }
+	var rules []rule
+	for _, v := range values {
+		pattern, value, ok := strings.Cut(v, "=")
+		if !ok || whole != nil && whole(v) {
+			value, pattern, ok = v, "", false
+		}
+		if err := check(value); err != nil {
+			return nil, err
+		}
+		if !ok {
+			def = value
+			continue
+		}
+		rules = append(rules, rule{pattern, value})
+	}
+
+	return func(path string) string {
+		for _, r := range rules {
+			if strings.ContainsRune(r.pattern, filepath.Separator) {
+				if source.Match(r.pattern, path) {
+					return r.value
+				}
+			} else if ok, _ := filepath.Match(r.pattern, filepath.Base(path)); ok {
+				return r.value
+			}
+		}
+		return def
+	}, nil
+}
+
+// applyDefaults sets the flags listed in the configuration file that were not
+// given on the command line, as if they had been.
+func applyDefaults(flags *pflag.FlagSet, defaults []config.Default, onCommandLine map[string]bool) error {
+	for _, d := range defaults {
+		f := flags.Lookup(d.Flag)
+		if f == nil || d.Flag == "config" {
+			return d.Pos.Errorf("flag desconhecida --%s", d.Flag)
+		}
+		if onCommandLine[d.Flag] {
+			continue
+		}
+		for _, v := range d.Values {
+			if err := flags.Set(d.Flag, v); err != nil {
+				return d.Pos.Errorf("valor inválido %q para --%s (esperado %s)", v, d.Flag, f.Value.Type())
+			}
+		}
+		configured[d.Flag] = d.Pos
+	}
+	return nil
+}
+
+// flagError points err at the key of the configuration file that set flag,
+// if it was set there.
+func flagError(flag string, err error) error {
+	if p, ok := configured[flag]; ok {
+		return p.Errorf("%v", err)
+	}
+	return err
+}
+
+// multilineOnCommandLine reports whether a multiline rule was given on the
+// command line, which then replaces the rules of configuration file sources.
+func multilineOnCommandLine(onCommandLine map[string]bool) bool {
+	return onCommandLine["multiline-start"] || onCommandLine["multiline-timestamp"] || onCommandLine["multiline-continue"]
+}
+
+// watchFile signals when the file at path changes, checking its size and
+// modification time every interval until ctx is done.
+func watchFile(ctx context.Context, path string, interval time.Duration) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+	stat := func() (time.Time, int64) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, -1
+		}
+		return info.ModTime(), info.Size()
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		mod, size := stat()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			m, s := stat()
+			if s < 0 || m.Equal(mod) && s == size {
+				continue
+			}
+			mod, size = m, s
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return changed
+}
 
+// lineTime returns the time of a line, set by its parser or detected in
+// its text.
+func lineTime(l logline.Line) (time.Time, bool) {
+	return l.Time, !l.Time.IsZero()
 }
 
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"logagg/internal/config"
+	"logagg/internal/filter"
+	"logagg/internal/logline"
+	"logagg/internal/multiline"
+	"logagg/internal/source"
+	"sync"
+)
+
+// sourceSettings is a source of the configuration file with its settings
+// compiled once and a context of its own, so a reload can stop its readers
+// without touching the others.
+type sourceSettings struct {
+	config.Source
+	joiner  *multiline.Joiner
+	matcher *filter.Matcher
+	ctx     context.Context
+	cancel  context.CancelFunc
+	// files counts the files of the source whose lines are still being
+	// passed on.
+	files sync.WaitGroup
+}
+
+// track passes on the lines of a file of the source, counting the file
+// until they are all handed over.
+func (ss *sourceSettings) track(in <-chan logline.Line) <-chan logline.Line {
+	out := make(chan logline.Line)
+	ss.files.Add(1)
+	go func() {
+		defer ss.files.Done()
+		defer close(out)
+		for l := range in {
+			out <- l
+		}
+	}()
+	return out
+}
+
+// sourceSet keeps the running sources of the configuration file in step
+// with the discoverer. Files are tied to their source by the name of the
+// spec that found them. It is not safe for concurrent use.
+type sourceSet struct {
+	ctx        context.Context
+	discoverer *source.Discoverer
+	ignoreCase bool
+	running    map[string]*sourceSettings
+}
+
+func newSourceSet(ctx context.Context, d *source.Discoverer, ignoreCase bool) *sourceSet {
+	return &sourceSet{ctx: ctx, discoverer: d, ignoreCase: ignoreCase, running: make(map[string]*sourceSettings)}
+}
+
+func (s *sourceSet) start(cs config.Source) {
+	ss := &sourceSettings{Source: cs}
+	// Both were validated when the file was loaded.
+	if cs.Multiline != nil {
+		ss.joiner, _ = multiline.Compile(*cs.Multiline)
+	}
+	if len(cs.Filter) > 0 || len(cs.Exclude) > 0 {
+		ss.matcher, _ = filter.Compile(filter.Options{Include: cs.Filter, Exclude: cs.Exclude, IgnoreCase: s.ignoreCase})
+	}
+	ss.ctx, ss.cancel = context.WithCancel(s.ctx)
+	s.running[cs.Name] = ss
+	s.discoverer.Add(source.Spec{Name: cs.Name, Pattern: cs.Path, Include: cs.IncludeFiles, Exclude: cs.ExcludeFiles})
+}
+
+// stop cancels the readers of the named source and waits until the lines
+// they sent have passed the rest of its pipeline, so none is lost and a
+// reader started again on the same files resumes right after them.
+func (s *sourceSet) stop(name string) {
+	ss := s.running[name]
+	ss.cancel()
+	ss.files.Wait()
+	delete(s.running, name)
+	s.discoverer.Remove(name)
+}
+
+// reload brings the running sources in line with sources: unchanged ones
+// keep their readers, changed and removed ones are stopped, and new and
+// changed ones are started, their files being picked up by the next scan.
+func (s *sourceSet) reload(sources []config.Source) (added, updated, removed int) {
+	next := make(map[string]bool)
+	for _, cs := range sources {
+		next[cs.Name] = true
+		ss, ok := s.running[cs.Name]
+		switch {
+		case !ok:
+			added++
+		case !ss.Source.Equal(cs):
+			s.stop(cs.Name)
+			updated++
+		default:
+			continue
+		}
+		s.start(cs)
+	}
+	for name := range s.running {
+		if !next[name] {
+			s.stop(name)
+			removed++
+		}
+	}
+	return added, updated, removed
+}
+
+// of returns the source that found the file at path. It reports false for
+// a file found by a source that has since been removed; files given on the
+// command line have no source and get nil.
+func (s *sourceSet) of(path string) (*sourceSettings, bool) {
+	spec, ok := s.discoverer.Origin(path)
+	if !ok || spec.Name == "" {
+		return nil, true
+	}
+	ss, ok := s.running[spec.Name]
+	return ss, ok
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+	"logagg/internal/aggregator"
+	"logagg/internal/checkpoint"
+	"logagg/internal/config"
+	"logagg/internal/logline"
+	"logagg/internal/multiline"
+	"logagg/internal/reader"
+	"logagg/internal/source"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatalf("failed to write to %s: %v", path, err)
+	}
+}
+
+func TestSourceSet_ReloadKeepsEveryLine(t *testing.T) {
+	dir := t.TempDir()
+	aPath, bPath := filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")
+	appendFile(t, aPath, "1 a\n x\n2 b\n")
+	appendFile(t, bPath, "b1\nb2\n")
+
+	a := config.Source{Name: "a", Path: aPath, Multiline: &multiline.Rule{Start: `^\d`}}
+	b := config.Source{Name: "b", Path: bPath}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	discoverer := source.NewDiscoverer(nil)
+	set := newSourceSet(ctx, discoverer, false)
+	if added, _, _ := set.reload([]config.Source{a, b}); added != 2 {
+		t.Fatalf("expected 2 sources added, got %d", added)
+	}
+
+	// The same stages as the command, minus parsing and filtering.
+	handoff := checkpoint.NewMemory()
+	sources := make(chan (<-chan logline.Line), 4)
+	scan := func() {
+		found, err := discoverer.Scan()
+		if err != nil {
+			t.Fatalf("Scan() unexpected error = %v", err)
+		}
+		for _, f := range found {
+			ss, ok := set.of(f)
+			if !ok || ss == nil {
+				t.Fatalf("no running source for %s", f)
+			}
+			ch := reader.Read(ss.ctx, f, reader.Options{Tail: true, Watch: reader.WatchPoll, PollInterval: 10 * time.Millisecond, Resume: handoff, Label: ss.Label})
+			if ss.joiner != nil {
+				ch = ss.joiner.Join(context.Background(), ch)
+			}
+			sources <- ss.track(ch)
+		}
+	}
+	lines := aggregator.AggregateStream(context.Background(), sources)
+
+	var got []logline.Line
+	until := func(want string) {
+		t.Helper()
+		timeout := time.After(2 * time.Second)
+		for {
+			for _, l := range got {
+				if l.Raw == want {
+					return
+				}
+			}
+			select {
+			case l := <-lines:
+				got = append(got, l)
+			case <-timeout:
+				t.Fatalf("timed out waiting for %q", want)
+			}
+		}
+	}
+
+	scan()
+	until("b2")
+	until("1 a\n x")
+
+	// " y" continues the event still held by the joiner of a.
+	appendFile(t, aPath, " y\n")
+	appendFile(t, bPath, "b3\n")
+	until("b3")
+
+	a.Label = "A"
+	if added, updated, removed := set.reload([]config.Source{a, b}); added != 0 || updated != 1 || removed != 0 {
+		t.Fatalf("expected 1 source changed, got %d added, %d changed, %d removed", added, updated, removed)
+	}
+	scan()
+
+	appendFile(t, aPath, "3 c\n4 d\n")
+	appendFile(t, bPath, "b4\n")
+	until("b4")
+	until("3 c")
+
+	cancel()
+	close(sources)
+	for l := range lines {
+		got = append(got, l)
+	}
+
+	count := make(map[string]int)
+	for _, l := range got {
+		for _, part := range strings.Split(l.Raw, "\n") {
+			count[part]++
+		}
+		if strings.HasPrefix(l.Raw, "3 c") && l.Name() != "A" {
+			t.Errorf("expected lines read after the reload to be labelled A, got %q", l.Name())
+		}
+	}
+	for _, want := range []string{"1 a", " x", "2 b", " y", "3 c", "4 d", "b1", "b2", "b3", "b4"} {
+		if count[want] != 1 {
+			t.Errorf("expected %q exactly once, got %d times", want, count[want])
+		}
+		delete(count, want)
+	}
+	for extra := range count {
+		t.Errorf("unexpected line %q", extra)
+	}
+}
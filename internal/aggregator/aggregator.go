@@ -5,28 +5,58 @@ import (
 	"sync"
 )
 
-func Aggregate(ctx context.Context, channels ...<-chan string) chan string {
-	out := make(chan string)
+func Aggregate[T any](ctx context.Context, channels ...<-chan T) chan T {
+	return AggregateStream(ctx, sourcesOf(channels))
+}
+
+// AggregateStream fans in channels received from sources, so channels can
+// join while aggregation is already running, e.g. files discovered after
+// startup. A channel leaves when it is closed, so a source is removed at
+// runtime by cancelling the context its producer runs under while the
+// others keep flowing. The output closes once sources is closed and every
+// channel received from it has ended.
+func AggregateStream[T any](ctx context.Context, sources <-chan (<-chan T)) chan T {
+	out := make(chan T)
 	var wg sync.WaitGroup
 
-	for _, ch := range channels {
-		wg.Add(1)
-		go func(ch <-chan string) {
-			defer wg.Done()
-			for msg := range ch {
-				select {
-				case out <- msg:
-				case <-ctx.Done():
-					return
-				}
+	forward := func(ch <-chan T) {
+		defer wg.Done()
+		for msg := range ch {
+			select {
+			case out <- msg:
+			case <-ctx.Done():
+				return
 			}
-		}(ch)
+		}
 	}
 
 	go func() {
-		wg.Wait()
-		close(out)
+		defer close(out)
+		defer wg.Wait()
+		for {
+			select {
+			case ch, ok := <-sources:
+				if !ok {
+					return
+				}
+				wg.Add(1)
+				go forward(ch)
+			case <-ctx.Done():
+				return
+			}
+		}
 	}()
 
 	return out
 }
+
+// sourcesOf turns a fixed list of channels into an already closed stream
+// of sources.
+func sourcesOf[T any](channels []<-chan T) <-chan (<-chan T) {
+	sources := make(chan (<-chan T), len(channels))
+	for _, ch := range channels {
+		sources <- ch
+	}
+	close(sources)
+	return sources
+}
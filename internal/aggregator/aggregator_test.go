@@ -177,7 +177,7 @@ func TestAggregate_NoChannels(t *testing.T) {
 	defer cancel()
 
 	// Call Aggregate with no channels
-	result := Aggregate(ctx)
+	result := Aggregate[string](ctx)
 
 	// Should close immediately
 	timeout := time.After(1 * time.Second)
@@ -276,3 +276,112 @@ func TestAggregate_LargeVolume(t *testing.T) {
 		t.Errorf("expected %d messages, got %d", expectedCount, count)
 	}
 }
+
+func TestAggregateStream_SourcesJoinLater(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	sources := make(chan (<-chan string))
+	result := AggregateStream(ctx, sources)
+
+	first := make(chan string, 1)
+	first <- "from first"
+	close(first)
+	sources <- first
+
+	if msg := <-result; msg != "from first" {
+		t.Errorf("expected %q, got %q", "from first", msg)
+	}
+
+	// A source added after output started flowing is still aggregated.
+	second := make(chan string, 1)
+	second <- "from second"
+	close(second)
+	sources <- second
+
+	if msg := <-result; msg != "from second" {
+		t.Errorf("expected %q, got %q", "from second", msg)
+	}
+
+	close(sources)
+	if _, ok := <-result; ok {
+		t.Error("expected output to close after sources closed and drained")
+	}
+}
+
+func TestAggregateStream_WaitsForOpenChannels(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	ch := make(chan string)
+	sources := make(chan (<-chan string), 1)
+	sources <- ch
+	close(sources)
+
+	result := AggregateStream(ctx, sources)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		ch <- "late"
+		close(ch)
+	}()
+
+	var messages []string
+	for msg := range result {
+		messages = append(messages, msg)
+	}
+	if len(messages) != 1 {
+		t.Errorf("expected 1 message, got %v", messages)
+	}
+}
+
+func TestAggregateStream_SourcesLeave(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// Each source runs under its own context, as the readers of a
+	// configuration file source do.
+	produce := func(ctx context.Context, msg string) <-chan string {
+		ch := make(chan string)
+		go func() {
+			defer close(ch)
+			for ctx.Err() == nil {
+				select {
+				case ch <- msg:
+				case <-ctx.Done():
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}()
+		return ch
+	}
+
+	removedCtx, remove := context.WithCancel(ctx)
+	sources := make(chan (<-chan string), 2)
+	sources <- produce(removedCtx, "removed")
+	sources <- produce(ctx, "kept")
+	result := AggregateStream(ctx, sources)
+
+	seen := make(map[string]bool)
+	for !seen["removed"] || !seen["kept"] {
+		seen[<-result] = true
+	}
+
+	remove()
+	// A message already in flight may still arrive; after that only the
+	// remaining source is aggregated.
+	late := 0
+	for i := 0; i < 50; i++ {
+		msg, ok := <-result
+		if !ok {
+			t.Fatal("output closed after removing one source")
+		}
+		if msg == "removed" {
+			late++
+		}
+	}
+	if late > 2 {
+		t.Errorf("received %d messages from a removed source", late)
+	}
+}
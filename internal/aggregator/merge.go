@@ -0,0 +1,204 @@
+package aggregator
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// KeyFunc extracts the timestamp used to order a message.
+type KeyFunc[T any] func(msg T) (time.Time, bool)
+
+// Merge combines channels whose messages are already in chronological order
+// into a single globally chronological stream, using a k-way merge over the
+// head of each channel. It waits for every open channel to offer a message
+// before emitting, so it is meant for sources that end, not for tail mode.
+//
+// Messages without a timestamp keep the timestamp of the previous message
+// from the same channel, which keeps continuation lines next to the line
+// they belong to.
+func Merge[T any](ctx context.Context, key KeyFunc[T], channels ...<-chan T) chan T {
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+
+		last := make([]time.Time, len(channels))
+		var seq uint64
+		h := &itemHeap[T]{}
+
+		next := func(src int) bool {
+			select {
+			case msg, ok := <-channels[src]:
+				if !ok {
+					return true
+				}
+				if t, ok := key(msg); ok {
+					last[src] = t
+				}
+				seq++
+				heap.Push(h, item[T]{msg: msg, ts: last[src], src: src, seq: seq})
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for i := range channels {
+			if !next(i) {
+				return
+			}
+		}
+
+		for h.Len() > 0 {
+			it := heap.Pop(h).(item[T])
+			select {
+			case out <- it.msg:
+			case <-ctx.Done():
+				return
+			}
+			if !next(it.src) {
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// MergeWindow orders messages from live channels within a bounded lateness
+// window. A message is held until the watermark, the newest timestamp seen
+// minus window, passes it; messages arriving later than that are emitted
+// right away, out of order. When the input goes quiet the watermark keeps
+// advancing with the wall clock so buffered messages are not held forever.
+func MergeWindow[T any](ctx context.Context, key KeyFunc[T], window time.Duration, channels ...<-chan T) chan T {
+	return MergeWindowStream(ctx, key, window, sourcesOf(channels))
+}
+
+// MergeWindowStream is MergeWindow for channels received from sources
+// while merging is already running. As with AggregateStream, a channel
+// leaves when it is closed.
+func MergeWindowStream[T any](ctx context.Context, key KeyFunc[T], window time.Duration, sources <-chan (<-chan T)) chan T {
+	out := make(chan T)
+	in := make(chan item[T])
+	var wg sync.WaitGroup
+
+	tag := func(src int, ch <-chan T) {
+		defer wg.Done()
+		var last time.Time
+		for msg := range ch {
+			if t, ok := key(msg); ok {
+				last = t
+			}
+			select {
+			case in <- item[T]{msg: msg, ts: last, src: src}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	go func() {
+		defer close(in)
+		defer wg.Wait()
+		for src := 0; ; src++ {
+			select {
+			case ch, ok := <-sources:
+				if !ok {
+					return
+				}
+				wg.Add(1)
+				go tag(src, ch)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer close(out)
+
+		tick := window / 4
+		if tick < 10*time.Millisecond {
+			tick = 10 * time.Millisecond
+		}
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+
+		h := &itemHeap[T]{}
+		var seq uint64
+		var newest, arrival time.Time
+
+		emit := func(all bool) bool {
+			watermark := newest.Add(-window).Add(time.Since(arrival))
+			for h.Len() > 0 && (all || !(*h)[0].ts.After(watermark)) {
+				select {
+				case out <- heap.Pop(h).(item[T]).msg:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		}
+
+		for {
+			select {
+			case it, ok := <-in:
+				if !ok {
+					emit(true)
+					return
+				}
+				if newest.IsZero() || it.ts.After(newest) {
+					newest = it.ts
+				}
+				arrival = time.Now()
+				seq++
+				it.seq = seq
+				heap.Push(h, it)
+				if !emit(false) {
+					return
+				}
+			case <-ticker.C:
+				if !emit(false) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+type item[T any] struct {
+	msg T
+	ts  time.Time
+	src int
+	seq uint64
+}
+
+// itemHeap orders items by timestamp, falling back to arrival order so
+// messages with equal timestamps keep their relative order.
+type itemHeap[T any] []item[T]
+
+func (h itemHeap[T]) Len() int { return len(h) }
+
+func (h itemHeap[T]) Less(i, j int) bool {
+	if !h[i].ts.Equal(h[j].ts) {
+		return h[i].ts.Before(h[j].ts)
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h itemHeap[T]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *itemHeap[T]) Push(x any) { *h = append(*h, x.(item[T])) }
+
+func (h *itemHeap[T]) Pop() any {
+	old := *h
+	it := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return it
+}
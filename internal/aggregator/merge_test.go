@@ -0,0 +1,155 @@
+package aggregator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// prefixTime reads a "15:04:05" timestamp at the start of a message.
+func prefixTime(msg string) (time.Time, bool) {
+	t, err := time.Parse("15:04:05", strings.SplitN(msg, " ", 2)[0])
+	return t, err == nil
+}
+
+func feed(msgs ...string) <-chan string {
+	ch := make(chan string)
+	go func() {
+		for _, m := range msgs {
+			ch <- m
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+func TestMerge_ChronologicalOrder(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	app := feed("10:00:01 app start", "10:00:04 app ready", "10:00:06 app stop")
+	db := feed("10:00:02 db start", "10:00:03 db ready", "10:00:05 db slow query")
+
+	var got []string
+	for msg := range Merge(ctx, prefixTime, app, db) {
+		got = append(got, msg)
+	}
+
+	expected := []string{
+		"10:00:01 app start",
+		"10:00:02 db start",
+		"10:00:03 db ready",
+		"10:00:04 app ready",
+		"10:00:05 db slow query",
+		"10:00:06 app stop",
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d messages, got %d: %v", len(expected), len(got), got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("message %d: expected %q, got %q", i, expected[i], got[i])
+		}
+	}
+}
+
+func TestMerge_ContinuationLinesStayWithParent(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	app := feed("10:00:01 panic", "goroutine 1 [running]:", "10:00:05 restarted")
+	db := feed("10:00:02 db ok")
+
+	var got []string
+	for msg := range Merge(ctx, prefixTime, app, db) {
+		got = append(got, msg)
+	}
+
+	if len(got) != 4 || got[1] != "goroutine 1 [running]:" {
+		t.Errorf("expected continuation line right after its parent, got %v", got)
+	}
+}
+
+func TestMerge_NoChannels(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for msg := range Merge[string](ctx, prefixTime) {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
+
+func TestMergeWindow_ReordersWithinWindow(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	app := make(chan string)
+	db := make(chan string)
+
+	result := MergeWindow(ctx, prefixTime, time.Minute, app, db)
+
+	go func() {
+		app <- "10:00:03 late app line"
+		db <- "10:00:01 early db line"
+		app <- "10:00:02 middle app line"
+		close(app)
+		close(db)
+	}()
+
+	var got []string
+	for msg := range result {
+		got = append(got, msg)
+	}
+
+	expected := []string{"10:00:01 early db line", "10:00:02 middle app line", "10:00:03 late app line"}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d messages, got %d: %v", len(expected), len(got), got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("message %d: expected %q, got %q", i, expected[i], got[i])
+		}
+	}
+}
+
+func TestMergeWindow_FlushesWhenIdle(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	app := make(chan string)
+	defer close(app)
+
+	result := MergeWindow(ctx, prefixTime, 50*time.Millisecond, app)
+	app <- "10:00:01 only line"
+
+	select {
+	case msg := <-result:
+		if msg != "10:00:01 only line" {
+			t.Errorf("unexpected message %q", msg)
+		}
+	case <-time.After(time.Second):
+		t.Error("message was held past the lateness window")
+	}
+}
+
+func TestMergeWindowStream_SourcesJoinLater(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	sources := make(chan (<-chan string))
+	result := MergeWindowStream(ctx, prefixTime, time.Minute, sources)
+
+	sources <- feed("10:00:02 app")
+	sources <- feed("10:00:01 worker")
+	close(sources)
+
+	var got []string
+	for msg := range result {
+		got = append(got, msg)
+	}
+
+	if len(got) != 2 || got[0] != "10:00:01 worker" {
+		t.Errorf("expected the late source to be merged in order, got %v", got)
+	}
+}
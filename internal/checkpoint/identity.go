@@ -0,0 +1,59 @@
+package checkpoint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+)
+
+// FingerprintSize is how many leading bytes identify a file's content.
+const FingerprintSize = 1024
+
+// Identity tells files apart across renames: the device and inode stay the
+// same when logrotate moves a file, and the fingerprint of the first bytes
+// guards against an inode being reused for a different file.
+type Identity struct {
+	Dev         uint64 `json:"dev"`
+	Ino         uint64 `json:"ino"`
+	Fingerprint string `json:"fingerprint"`
+	// FingerprintLen is how many bytes the fingerprint covers; files
+	// shorter than FingerprintSize are fingerprinted as they are.
+	FingerprintLen int64 `json:"fingerprint_len"`
+}
+
+// Identify computes the identity of an open file without moving its read
+// position.
+func Identify(f *os.File) (Identity, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return Identity{}, err
+	}
+	dev, ino := fileID(info)
+
+	fp, n, err := fingerprint(f, FingerprintSize)
+	if err != nil {
+		return Identity{}, err
+	}
+	return Identity{Dev: dev, Ino: ino, Fingerprint: fp, FingerprintLen: n}, nil
+}
+
+func fingerprint(r io.ReaderAt, size int64) (string, int64, error) {
+	buf := make([]byte, size)
+	n, err := r.ReadAt(buf, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", 0, err
+	}
+	sum := sha256.Sum256(buf[:n])
+	return hex.EncodeToString(sum[:]), int64(n), nil
+}
+
+// sameContent reports whether f starts with the bytes fingerprinted in id.
+func sameContent(f io.ReaderAt, id Identity) bool {
+	if id.FingerprintLen == 0 {
+		return false
+	}
+	fp, n, err := fingerprint(f, id.FingerprintLen)
+	return err == nil && n == id.FingerprintLen && fp == id.Fingerprint
+}
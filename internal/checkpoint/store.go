@@ -0,0 +1,271 @@
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// entryTTL is how long a file that is no longer read keeps its entry.
+// Rotated files would otherwise pile up in the state file forever.
+const entryTTL = 7 * 24 * time.Hour
+
+// Entry is the saved position of one file.
+type Entry struct {
+	Identity
+	// Path is informational; files are matched by identity, not by name.
+	Path    string    `json:"path"`
+	Offset  int64     `json:"offset"`
+	Line    int64     `json:"line"`
+	Updated time.Time `json:"updated"`
+}
+
+type state struct {
+	Version int      `json:"version"`
+	Entries []*Entry `json:"entries"`
+}
+
+// Store keeps the last emitted offset of every file in a local state file,
+// so a restarted logagg resumes where it stopped instead of re-reading
+// everything. It is safe for concurrent use.
+type Store struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]*Entry
+	dirty   bool
+}
+
+func key(id Identity) string {
+	if id.Ino != 0 {
+		return fmt.Sprintf("%d:%d", id.Dev, id.Ino)
+	}
+	return "fp:" + id.Fingerprint
+}
+
+// Open loads the state file at path. A missing file is an empty store.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, entries: make(map[string]*Entry)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("arquivo de checkpoint inválido %s: %w", path, err)
+	}
+	for _, e := range st.Entries {
+		s.entries[key(e.Identity)] = e
+	}
+	return s, nil
+}
+
+// NewMemory returns a store that is never written to disk. It lets the
+// readers of a file restarted within one run resume where the previous
+// ones stopped.
+func NewMemory() *Store {
+	return &Store{entries: make(map[string]*Entry)}
+}
+
+// Lookup identifies f and returns the saved position for it, if any. A file
+// is recognised by device and inode, which survive a rename, as long as its
+// first bytes still match; failing that, by the fingerprint alone, which
+// catches rotation schemes that copy the file.
+func (s *Store) Lookup(f *os.File) (Identity, Entry, bool) {
+	id, err := Identify(f)
+	if err != nil {
+		return Identity{}, Entry{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key(id)
+	if e, ok := s.entries[k]; ok && sameContent(f, e.Identity) {
+		return id, *e, true
+	}
+	for old, e := range s.entries {
+		if e.FingerprintLen == FingerprintSize && sameContent(f, e.Identity) {
+			// Re-key the entry so the next update replaces it.
+			delete(s.entries, old)
+			s.entries[k] = e
+			s.dirty = true
+			return id, *e, true
+		}
+	}
+	return id, Entry{}, false
+}
+
+// Update records that everything before offset in the file with the given
+// identity has been emitted.
+func (s *Store) Update(id Identity, path string, offset, line int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key(id)] = &Entry{
+		Identity: id,
+		Path:     path,
+		Offset:   offset,
+		Line:     line,
+		Updated:  time.Now(),
+	}
+	s.dirty = true
+}
+
+// Position is where a line read from a file ends. Lines carry it through
+// the pipeline and it is committed once the line is written or dropped,
+// so lines still in flight when logagg stops are read again on restart.
+type Position struct {
+	ID   Identity
+	Path string
+	// Offset is just past the line and Line its number, 0 when unknown.
+	Offset int64
+	Line   int64
+
+	cursor *Cursor
+	seq    uint64
+}
+
+// Commit records that the line is done with, written or dropped. The
+// position is saved once the lines before it in the file are done too.
+func (p *Position) Commit() {
+	p.finish(p)
+}
+
+// Release records that the line is done with but its position must not be
+// saved, because a later position carries it on, as for the lines joined
+// into a multi-line event saved at its last line.
+func (p *Position) Release() {
+	p.finish(nil)
+}
+
+func (p *Position) finish(save *Position) {
+	if p == nil || p.cursor == nil {
+		return
+	}
+	p.cursor.finish(p.seq, save)
+}
+
+// Cursor hands out the positions of the lines of one file and saves them
+// in order: lines may be done out of order, a filtered line before a
+// printed one that is still on its way, and a position is only saved once
+// every line before it is done. It is safe for concurrent use.
+type Cursor struct {
+	store *Store
+
+	mu sync.Mutex
+	// next is the sequence number of the next position handed out, and
+	// every position before saved is done.
+	next, saved uint64
+	// done holds the positions done ahead of saved, nil for released ones.
+	done map[uint64]*Position
+}
+
+// Cursor returns a cursor saving the positions of one file in s.
+func (s *Store) Cursor() *Cursor {
+	return &Cursor{store: s, done: make(map[uint64]*Position)}
+}
+
+// Next returns the position of the next line of the file.
+func (c *Cursor) Next(id Identity, path string, offset, line int64) *Position {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p := &Position{ID: id, Path: path, Offset: offset, Line: line, cursor: c, seq: c.next}
+	c.next++
+	return p
+}
+
+func (c *Cursor) finish(seq uint64, save *Position) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.done[seq] = save
+	var last *Position
+	for {
+		p, ok := c.done[c.saved]
+		if !ok {
+			break
+		}
+		delete(c.done, c.saved)
+		c.saved++
+		if p != nil {
+			last = p
+		}
+	}
+	if last != nil {
+		c.store.Update(last.ID, last.Path, last.Offset, last.Line)
+	}
+}
+
+// Flush writes the store to disk if it changed. The file is replaced
+// atomically so a crash never leaves a half written state behind.
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	if !s.dirty || s.path == "" {
+		s.mu.Unlock()
+		return nil
+	}
+
+	st := state{Version: 1}
+	for k, e := range s.entries {
+		if time.Since(e.Updated) > entryTTL {
+			delete(s.entries, k)
+			continue
+		}
+		copied := *e
+		st.Entries = append(st.Entries, &copied)
+	}
+	s.dirty = false
+	s.mu.Unlock()
+
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Run flushes the store every interval until ctx is done, then flushes one
+// last time. Flush errors are passed to onError when it is not nil.
+func (s *Store) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	flush := func() {
+		if err := s.Flush(); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			flush()
+			return
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
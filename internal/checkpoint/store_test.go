@@ -0,0 +1,193 @@
+package checkpoint
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) *os.File {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestStore_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	f := writeFile(t, filepath.Join(dir, "app.log"), "line 1\nline 2\n")
+
+	s, err := Open(statePath)
+	if err != nil {
+		t.Fatalf("Open() unexpected error = %v", err)
+	}
+	id, _, ok := s.Lookup(f)
+	if ok {
+		t.Fatal("Lookup() found an entry in an empty store")
+	}
+	s.Update(id, f.Name(), 7, 1)
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush() unexpected error = %v", err)
+	}
+
+	reopened, err := Open(statePath)
+	if err != nil {
+		t.Fatalf("Open() unexpected error = %v", err)
+	}
+	_, e, ok := reopened.Lookup(f)
+	if !ok {
+		t.Fatal("Lookup() did not find the saved entry")
+	}
+	if e.Offset != 7 || e.Line != 1 {
+		t.Errorf("expected offset 7 line 1, got offset %d line %d", e.Offset, e.Line)
+	}
+}
+
+func TestCursor_SavesInOrder(t *testing.T) {
+	f := writeFile(t, filepath.Join(t.TempDir(), "app.log"), "one\ntwo\nthree\nfour\n")
+	s := NewMemory()
+	id, _, _ := s.Lookup(f)
+	c := s.Cursor()
+	one, two, three, four := c.Next(id, f.Name(), 4, 1), c.Next(id, f.Name(), 8, 2), c.Next(id, f.Name(), 14, 3), c.Next(id, f.Name(), 19, 4)
+
+	saved := func() int64 {
+		_, e, _ := s.Lookup(f)
+		return e.Line
+	}
+	steps := []struct {
+		name string
+		done func()
+		want int64
+	}{
+		// two is dropped while one is still on its way.
+		{"dropped ahead", two.Commit, 0},
+		{"earlier written", one.Commit, 2},
+		// three was joined into an event saved at four.
+		{"released", three.Release, 2},
+		{"event written", four.Commit, 4},
+	}
+	for _, st := range steps {
+		st.done()
+		if got := saved(); got != st.want {
+			t.Errorf("%s: expected line %d saved, got %d", st.name, st.want, got)
+		}
+	}
+}
+
+func TestStore_RecognisesRenamedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	f := writeFile(t, path, "first line\n")
+
+	s, _ := Open(filepath.Join(dir, "state.json"))
+	id, _, _ := s.Lookup(f)
+	s.Update(id, path, 11, 1)
+
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("failed to rename: %v", err)
+	}
+	fresh := writeFile(t, path, "first line of the new file\n")
+
+	rotated, err := os.Open(path + ".1")
+	if err != nil {
+		t.Fatalf("failed to open rotated file: %v", err)
+	}
+	defer rotated.Close()
+
+	if _, e, ok := s.Lookup(rotated); !ok || e.Offset != 11 {
+		t.Errorf("expected the rotated file to keep its position, got %+v, %v", e, ok)
+	}
+	if _, _, ok := s.Lookup(fresh); ok {
+		t.Error("expected the new file at the old path to start from scratch")
+	}
+}
+
+func TestStore_RejectsReusedInode(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	f := writeFile(t, path, "original content\n")
+
+	s, _ := Open(filepath.Join(dir, "state.json"))
+	id, _, _ := s.Lookup(f)
+	s.Update(id, path, 17, 1)
+
+	// Same inode, different content: overwritten in place.
+	f2 := writeFile(t, path, "something else entirely\n")
+	if _, _, ok := s.Lookup(f2); ok {
+		t.Error("expected a file with different content not to match")
+	}
+}
+
+func TestStore_FingerprintFallback(t *testing.T) {
+	dir := t.TempDir()
+	content := strings.Repeat("x", FingerprintSize) + "\n"
+	f := writeFile(t, filepath.Join(dir, "app.log"), content)
+
+	s, _ := Open(filepath.Join(dir, "state.json"))
+	id, _, _ := s.Lookup(f)
+	s.Update(id, f.Name(), int64(len(content)), 1)
+
+	// A copy has a different inode but the same leading bytes.
+	c := writeFile(t, filepath.Join(dir, "app.log.1"), content)
+	if _, e, ok := s.Lookup(c); !ok || e.Offset != int64(len(content)) {
+		t.Errorf("expected the copy to be recognised by fingerprint, got %+v, %v", e, ok)
+	}
+}
+
+func TestNewMemory(t *testing.T) {
+	f := writeFile(t, filepath.Join(t.TempDir(), "app.log"), "line 1\nline 2\n")
+
+	s := NewMemory()
+	id, _, _ := s.Lookup(f)
+	s.Update(id, f.Name(), 7, 1)
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush() unexpected error = %v", err)
+	}
+
+	_, e, ok := s.Lookup(f)
+	if !ok || e.Offset != 7 || e.Line != 1 {
+		t.Errorf("Lookup() = %+v, %v; want offset 7, line 1", e, ok)
+	}
+}
+
+func TestOpen_InvalidState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	os.WriteFile(path, []byte("{not json"), 0o644)
+
+	if _, err := Open(path); err == nil {
+		t.Error("Open() error = nil, want error for invalid JSON")
+	}
+}
+
+func TestStore_RunFlushesOnShutdown(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	f := writeFile(t, filepath.Join(dir, "app.log"), "x\n")
+
+	s, _ := Open(statePath)
+	id, _, _ := s.Lookup(f)
+	s.Update(id, f.Name(), 2, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx, time.Hour, func(err error) { t.Errorf("flush error: %v", err) })
+		close(done)
+	}()
+	cancel()
+	<-done
+
+	if _, err := os.Stat(statePath); err != nil {
+		t.Errorf("expected state file after shutdown: %v", err)
+	}
+}
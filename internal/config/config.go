@@ -0,0 +1,439 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"logagg/internal/multiline"
+	"logagg/internal/parser"
+	"logagg/internal/timestamp"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the content of a configuration file:
+//
+//	sources:
+//	  - name: api
+//	    path: /var/log/api/*.log
+//	    label: api
+//	    format: json
+//	    timezone: UTC
+//	    exclude: ["healthz"]
+//	  - name: worker
+//	    path: /var/log/worker.log
+//	    multiline:
+//	      start: '^\d{4}-'
+//	output:
+//	  format: text
+//	  color: auto
+//	defaults:
+//	  level: warn
+//	  tail: true
+type Config struct {
+	Sources []Source
+	// Defaults holds values for command-line flags, applied only to the
+	// flags not given on the command line. The output section is folded
+	// into it as the output, color and template flags.
+	Defaults []Default
+}
+
+// Source is a named set of files read with settings of their own. Empty
+// settings fall back to the command-line flags.
+type Source struct {
+	// Name identifies the source; it is unique within the file.
+	Name string
+	// Path is a file, glob pattern or directory, as given to --files.
+	Path string
+	// Label, when set, replaces the file name in the output and in the
+	// source field of queries.
+	Label string
+	// IncludeFiles and ExcludeFiles select files found through globs and
+	// directories by name, like --include-files and --exclude-files.
+	IncludeFiles []string
+	ExcludeFiles []string
+	// Filter and Exclude keep and drop lines of this source by regular
+	// expression, on top of --filter and --exclude.
+	Filter  []string
+	Exclude []string
+	// Format is checked by CheckFormats rather than on load.
+	Format          string
+	TimestampFormat string
+	Timezone        string
+	// Multiline, when set, replaces the --multiline-* flags for this
+	// source.
+	Multiline *multiline.Rule
+
+	Pos       Pos
+	formatPos Pos
+}
+
+// Equal reports whether s and o describe the same source with the same
+// settings, wherever they are written in the file.
+func (s Source) Equal(o Source) bool {
+	s.Pos, o.Pos = Pos{}, Pos{}
+	s.formatPos, o.formatPos = Pos{}, Pos{}
+	return reflect.DeepEqual(s, o)
+}
+
+// Default is the value of a flag set in the configuration file. Lists are
+// kept as several values, as if the flag had been repeated.
+type Default struct {
+	Flag   string
+	Values []string
+	Pos    Pos
+}
+
+// Pos locates a key in the configuration file.
+type Pos struct {
+	File   string
+	Line   int
+	Column int
+	// Key is the path of the key, such as sources[1].format.
+	Key string
+}
+
+// Errorf returns an *Error located at p.
+func (p Pos) Errorf(format string, args ...any) error {
+	return &Error{Pos: p, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Error is a problem found in a configuration file, reported with the file,
+// line, column and key it concerns.
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	if e.Pos.Key == "" {
+		return fmt.Sprintf("%s:%d:%d: %s", e.Pos.File, e.Pos.Line, e.Pos.Column, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", e.Pos.File, e.Pos.Line, e.Pos.Column, e.Pos.Key, e.Msg)
+}
+
+// CheckFormats validates the format of every source. It is left out of
+// Load because grok expressions may use patterns from files loaded after
+// the configuration, through --grok-patterns or its default in the file.
+func (c *Config) CheckFormats() error {
+	for _, s := range c.Sources {
+		if s.Format == "" {
+			continue
+		}
+		if _, err := parser.New(s.Format); err != nil {
+			return s.formatPos.Errorf("%v", err)
+		}
+	}
+	return nil
+}
+
+// Load reads and validates the configuration file at path, except for the
+// formats of its sources; see CheckFormats.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler configuração: %w", err)
+	}
+	return Parse(path, data)
+}
+
+// Parse validates data, read from the file named file.
+func Parse(file string, data []byte) (*Config, error) {
+	var root yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&root); err != nil {
+		if errors.Is(err, io.EOF) {
+			return &Config{}, nil
+		}
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	d := decoder{file: file}
+	c := &Config{}
+	doc := root.Content[0]
+	err := d.mapping(doc, "", map[string]func(string, *yaml.Node) error{
+		"sources": func(key string, n *yaml.Node) error {
+			if n.Kind != yaml.SequenceNode {
+				return d.errorf(n, key, "esperada uma lista de fontes")
+			}
+			names := make(map[string]bool)
+			for i, item := range n.Content {
+				s, err := d.source(item, fmt.Sprintf("%s[%d]", key, i))
+				if err != nil {
+					return err
+				}
+				if names[s.Name] {
+					return d.errorf(item, fmt.Sprintf("%s[%d].name", key, i), "fonte %q repetida", s.Name)
+				}
+				names[s.Name] = true
+				c.Sources = append(c.Sources, s)
+			}
+			return nil
+		},
+		"output": func(key string, n *yaml.Node) error {
+			flags := map[string]string{"format": "output", "color": "color", "template": "template"}
+			handlers := make(map[string]func(string, *yaml.Node) error)
+			for k, flag := range flags {
+				handlers[k] = func(key string, n *yaml.Node) error {
+					v, err := d.str(n, key)
+					if err != nil {
+						return err
+					}
+					c.Defaults = append(c.Defaults, Default{Flag: flag, Values: []string{v}, Pos: d.pos(n, key)})
+					return nil
+				}
+			}
+			return d.mapping(n, key, handlers)
+		},
+		"defaults": func(key string, n *yaml.Node) error {
+			if n.Kind != yaml.MappingNode {
+				return d.errorf(n, key, "esperado um mapa de flags")
+			}
+			for i := 0; i < len(n.Content); i += 2 {
+				k, v := n.Content[i], n.Content[i+1]
+				path := key + "." + k.Value
+				values, err := d.strs(v, path)
+				if err != nil {
+					return err
+				}
+				c.Defaults = append(c.Defaults, Default{Flag: k.Value, Values: values, Pos: d.pos(k, path)})
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+type decoder struct {
+	file string
+}
+
+func (d decoder) pos(n *yaml.Node, key string) Pos {
+	return Pos{File: d.file, Line: n.Line, Column: n.Column, Key: key}
+}
+
+func (d decoder) errorf(n *yaml.Node, key, format string, args ...any) error {
+	return d.pos(n, key).Errorf(format, args...)
+}
+
+// mapping calls the handler of each key of n, reporting keys without one.
+func (d decoder) mapping(n *yaml.Node, path string, handlers map[string]func(string, *yaml.Node) error) error {
+	if n.Kind != yaml.MappingNode {
+		return d.errorf(n, path, "esperado um mapa")
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		key := k.Value
+		if path != "" {
+			key = path + "." + k.Value
+		}
+		h, ok := handlers[k.Value]
+		if !ok {
+			return d.errorf(k, key, "chave desconhecida")
+		}
+		if seen[k.Value] {
+			return d.errorf(k, key, "chave repetida")
+		}
+		seen[k.Value] = true
+		if err := h(key, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d decoder) str(n *yaml.Node, key string) (string, error) {
+	if n.Kind != yaml.ScalarNode || n.Tag == "!!null" {
+		return "", d.errorf(n, key, "esperado um texto")
+	}
+	return n.Value, nil
+}
+
+// strs accepts a single value or a list of values.
+func (d decoder) strs(n *yaml.Node, key string) ([]string, error) {
+	if n.Kind != yaml.SequenceNode {
+		v, err := d.str(n, key)
+		return []string{v}, err
+	}
+	values := make([]string, 0, len(n.Content))
+	for i, item := range n.Content {
+		v, err := d.str(item, fmt.Sprintf("%s[%d]", key, i))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func (d decoder) source(n *yaml.Node, path string) (Source, error) {
+	s := Source{Pos: d.pos(n, path)}
+	text := func(dst *string, check func(string) error) func(string, *yaml.Node) error {
+		return func(key string, n *yaml.Node) error {
+			v, err := d.str(n, key)
+			if err != nil {
+				return err
+			}
+			if check != nil {
+				if err := check(v); err != nil {
+					return d.errorf(n, key, "%v", err)
+				}
+			}
+			*dst = v
+			return nil
+		}
+	}
+	list := func(dst *[]string, check func(string) error) func(string, *yaml.Node) error {
+		return func(key string, n *yaml.Node) error {
+			v, err := d.strs(n, key)
+			if err != nil {
+				return err
+			}
+			for i, p := range v {
+				if err := check(p); err != nil {
+					if n.Kind == yaml.SequenceNode {
+						return d.errorf(n.Content[i], fmt.Sprintf("%s[%d]", key, i), "%v", err)
+					}
+					return d.errorf(n, key, "%v", err)
+				}
+			}
+			*dst = v
+			return nil
+		}
+	}
+
+	err := d.mapping(n, path, map[string]func(string, *yaml.Node) error{
+		"name":          text(&s.Name, nil),
+		"path":          text(&s.Path, nil),
+		"label":         text(&s.Label, nil),
+		"include_files": list(&s.IncludeFiles, checkGlob),
+		"exclude_files": list(&s.ExcludeFiles, checkGlob),
+		"filter":        list(&s.Filter, checkRegexp),
+		"exclude":       list(&s.Exclude, checkRegexp),
+		"format": func(key string, n *yaml.Node) error {
+			s.formatPos = d.pos(n, key)
+			return text(&s.Format, nil)(key, n)
+		},
+		"timestamp_format": text(&s.TimestampFormat, func(v string) error {
+			_, err := timestamp.ParseLayout(v)
+			return err
+		}),
+		"timezone": text(&s.Timezone, func(v string) error {
+			if _, err := time.LoadLocation(v); err != nil {
+				return fmt.Errorf("fuso horário inválido %q: %w", v, err)
+			}
+			return nil
+		}),
+		"multiline": func(key string, n *yaml.Node) error {
+			r, err := d.multiline(n, key)
+			s.Multiline = r
+			return err
+		},
+	})
+	if err != nil {
+		return s, err
+	}
+	if s.Name == "" {
+		return s, d.errorf(n, path+".name", "nome da fonte obrigatório")
+	}
+	if s.Path == "" {
+		return s, d.errorf(n, path+".path", "caminho da fonte obrigatório")
+	}
+	return s, nil
+}
+
+// multiline reads a multiline rule, starting from the defaults of the
+// --multiline-* flags.
+func (d decoder) multiline(n *yaml.Node, path string) (*multiline.Rule, error) {
+	r := &multiline.Rule{MaxLines: 500, MaxBytes: 1 << 20, Timeout: time.Second}
+	integer := func(dst *int) func(string, *yaml.Node) error {
+		return func(key string, n *yaml.Node) error {
+			v, err := d.str(n, key)
+			if err != nil {
+				return err
+			}
+			i, err := strconv.Atoi(v)
+			if err != nil || i < 0 {
+				return d.errorf(n, key, "esperado um número inteiro não negativo, recebido %q", v)
+			}
+			*dst = i
+			return nil
+		}
+	}
+	pattern := func(dst *string) func(string, *yaml.Node) error {
+		return func(key string, n *yaml.Node) error {
+			v, err := d.str(n, key)
+			if err != nil {
+				return err
+			}
+			if err := checkRegexp(v); err != nil {
+				return d.errorf(n, key, "%v", err)
+			}
+			*dst = v
+			return nil
+		}
+	}
+
+	err := d.mapping(n, path, map[string]func(string, *yaml.Node) error{
+		"start":    pattern(&r.Start),
+		"continue": pattern(&r.Continue),
+		"timestamp": func(key string, n *yaml.Node) error {
+			v, err := d.str(n, key)
+			if err != nil {
+				return err
+			}
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return d.errorf(n, key, "esperado true ou false, recebido %q", v)
+			}
+			r.StartTimestamp = b
+			return nil
+		},
+		"max_lines": integer(&r.MaxLines),
+		"max_bytes": integer(&r.MaxBytes),
+		"timeout": func(key string, n *yaml.Node) error {
+			v, err := d.str(n, key)
+			if err != nil {
+				return err
+			}
+			t, err := time.ParseDuration(v)
+			if err != nil || t < 0 {
+				return d.errorf(n, key, "duração inválida %q", v)
+			}
+			r.Timeout = t
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !r.Enabled() {
+		return nil, d.errorf(n, path, "informe start, continue ou timestamp")
+	}
+	return r, nil
+}
+
+func checkGlob(p string) error {
+	if _, err := filepath.Match(p, ""); err != nil {
+		return fmt.Errorf("padrão de arquivo inválido %q", p)
+	}
+	return nil
+}
+
+func checkRegexp(p string) error {
+	if _, err := regexp.Compile(p); err != nil {
+		return fmt.Errorf("padrão inválido %q: %w", p, err)
+	}
+	return nil
+}
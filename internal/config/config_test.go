@@ -0,0 +1,268 @@
+package config
+
+import (
+	"errors"
+	"logagg/internal/parser"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	data := `
+sources:
+  - name: api
+    path: /var/log/api/*.log
+    label: API
+    format: json
+    timezone: UTC
+    include_files: "*.log"
+    exclude_files: ["*.gz", "*.zst"]
+    filter: ["payments"]
+    exclude: healthz
+  - name: worker
+    path: /var/log/worker.log
+    timestamp_format: epoch_ms
+    multiline:
+      start: '^\d{4}-'
+      max_lines: 50
+      timeout: 2s
+output:
+  format: ndjson
+  color: never
+defaults:
+  level: warn
+  filter: [ERROR, FATAL]
+  tail: true
+`
+	c, err := Parse("logagg.yaml", []byte(data))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error = %v", err)
+	}
+
+	if len(c.Sources) != 2 {
+		t.Fatalf("expected 2 sources, got %d", len(c.Sources))
+	}
+	api := c.Sources[0]
+	if api.Name != "api" || api.Path != "/var/log/api/*.log" || api.Label != "API" || api.Format != "json" || api.Timezone != "UTC" {
+		t.Errorf("unexpected api source: %+v", api)
+	}
+	if !reflect.DeepEqual(api.IncludeFiles, []string{"*.log"}) || !reflect.DeepEqual(api.ExcludeFiles, []string{"*.gz", "*.zst"}) {
+		t.Errorf("unexpected file patterns: %v, %v", api.IncludeFiles, api.ExcludeFiles)
+	}
+	if !reflect.DeepEqual(api.Filter, []string{"payments"}) || !reflect.DeepEqual(api.Exclude, []string{"healthz"}) {
+		t.Errorf("unexpected line patterns: %v, %v", api.Filter, api.Exclude)
+	}
+	if api.Multiline != nil {
+		t.Errorf("api Multiline = %+v, want nil", api.Multiline)
+	}
+
+	worker := c.Sources[1]
+	if worker.TimestampFormat != "epoch_ms" {
+		t.Errorf("worker TimestampFormat = %q", worker.TimestampFormat)
+	}
+	m := worker.Multiline
+	if m == nil || m.Start != `^\d{4}-` || m.MaxLines != 50 || m.MaxBytes != 1<<20 || m.Timeout != 2*time.Second {
+		t.Errorf("unexpected worker multiline rule: %+v", m)
+	}
+
+	var got []string
+	for _, d := range c.Defaults {
+		got = append(got, d.Flag+"="+strings.Join(d.Values, ","))
+	}
+	want := []string{"output=ndjson", "color=never", "level=warn", "filter=ERROR,FATAL", "tail=true"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Defaults = %v, want %v", got, want)
+	}
+	if p := c.Defaults[3].Pos; p.Line != 24 || p.Key != "defaults.filter" {
+		t.Errorf("Defaults[3].Pos = %+v", p)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "unknown top-level key",
+			data: "sorces: []",
+			want: "c.yaml:1:1: sorces: chave desconhecida",
+		},
+		{
+			name: "unknown source key",
+			data: "sources:\n  - name: api\n    path: a.log\n    fromat: json",
+			want: "c.yaml:4:5: sources[0].fromat: chave desconhecida",
+		},
+		{
+			name: "invalid timezone",
+			data: "sources:\n  - name: api\n    path: a.log\n    timezone: Mars/Olympus",
+			want: `c.yaml:4:15: sources[0].timezone: fuso horário inválido "Mars/Olympus"`,
+		},
+		{
+			name: "invalid pattern in a list",
+			data: "sources:\n  - name: api\n    path: a.log\n    exclude: [ok, \"(\"]",
+			want: `c.yaml:4:19: sources[0].exclude[1]: padrão inválido "("`,
+		},
+		{
+			name: "missing name",
+			data: "sources:\n  - path: a.log",
+			want: "c.yaml:2:5: sources[0].name: nome da fonte obrigatório",
+		},
+		{
+			name: "missing path",
+			data: "sources:\n  - name: api",
+			want: "c.yaml:2:5: sources[0].path: caminho da fonte obrigatório",
+		},
+		{
+			name: "repeated name",
+			data: "sources:\n  - name: api\n    path: a.log\n  - name: api\n    path: b.log",
+			want: `c.yaml:4:5: sources[1].name: fonte "api" repetida`,
+		},
+		{
+			name: "sources not a list",
+			data: "sources:\n  name: api",
+			want: "c.yaml:2:3: sources: esperada uma lista de fontes",
+		},
+		{
+			name: "list where text is expected",
+			data: "sources:\n  - name: [api]\n    path: a.log",
+			want: "c.yaml:2:11: sources[0].name: esperado um texto",
+		},
+		{
+			name: "multiline without a rule",
+			data: "sources:\n  - name: api\n    path: a.log\n    multiline:\n      max_lines: 10",
+			want: "c.yaml:5:7: sources[0].multiline: informe start, continue ou timestamp",
+		},
+		{
+			name: "bad multiline duration",
+			data: "sources:\n  - name: api\n    path: a.log\n    multiline:\n      start: x\n      timeout: soon",
+			want: `c.yaml:6:16: sources[0].multiline.timeout: duração inválida "soon"`,
+		},
+		{
+			name: "unknown output key",
+			data: "output:\n  colour: never",
+			want: "c.yaml:2:3: output.colour: chave desconhecida",
+		},
+		{
+			name: "nested default",
+			data: "defaults:\n  level:\n    min: warn",
+			want: "c.yaml:3:5: defaults.level: esperado um texto",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse("c.yaml", []byte(tt.data))
+			if err == nil {
+				t.Fatal("Parse() error = nil, want error")
+			}
+			var cerr *Error
+			if !errors.As(err, &cerr) {
+				t.Fatalf("Parse() error = %T, want *Error", err)
+			}
+			if !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %q, want prefix %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_CheckFormats(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "known format",
+			data: "sources:\n  - name: api\n    path: a.log\n    format: json",
+		},
+		{
+			name: "unknown format",
+			data: "sources:\n  - name: api\n    path: a.log\n    format: xml",
+			want: `c.yaml:4:13: sources[0].format: formato de log desconhecido "xml"`,
+		},
+		{
+			name: "grok pattern not loaded",
+			data: "sources:\n  - name: api\n    path: a.log\n    format: \"grok:%{NOT_LOADED:x}\"",
+			want: "c.yaml:4:13: sources[0].format: ",
+		},
+		{
+			// As loaded from --grok-patterns after the file was read.
+			name: "grok pattern loaded later",
+			data: "sources:\n  - name: api\n    path: a.log\n    format: \"grok:%{CONFIG_TEST_ID:x}\"",
+		},
+	}
+	parser.Patterns.Add("CONFIG_TEST_ID", `\d+`)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Parse("c.yaml", []byte(tt.data))
+			if err != nil {
+				t.Fatalf("Parse() unexpected error = %v", err)
+			}
+			err = c.CheckFormats()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("CheckFormats() unexpected error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("CheckFormats() error = %v, want prefix %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSource_Equal(t *testing.T) {
+	c, err := Parse("c.yaml", []byte("sources:\n  - name: api\n    path: a.log\n    exclude: [x]\n"))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error = %v", err)
+	}
+	moved, err := Parse("c.yaml", []byte("# moved\nsources:\n  - path: a.log\n    name: api\n    exclude: x\n"))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error = %v", err)
+	}
+
+	a, b := c.Sources[0], moved.Sources[0]
+	if !a.Equal(b) {
+		t.Errorf("Equal() = false for the same source written elsewhere: %+v, %+v", a, b)
+	}
+	b.Label = "API"
+	if a.Equal(b) {
+		t.Error("Equal() = true for sources with different labels")
+	}
+}
+
+func TestParse_Empty(t *testing.T) {
+	c, err := Parse("c.yaml", nil)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error = %v", err)
+	}
+	if len(c.Sources) != 0 || len(c.Defaults) != 0 {
+		t.Errorf("Parse() = %+v, want an empty config", c)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logagg.yaml")
+	os.WriteFile(path, []byte("sources:\n  - name: app\n    path: app.log\n"), 0o644)
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if len(c.Sources) != 1 || c.Sources[0].Pos.File != path {
+		t.Errorf("Load() = %+v", c)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Load() expected an error for a missing file")
+	}
+}
@@ -1,15 +1,31 @@
 package filter
 
-import "strings"
+import (
+	"logagg/internal/level"
+	"logagg/internal/logline"
+	"strings"
+	"time"
+)
 
-func Filter(ch <-chan string, filter string) <-chan string {
-	out := make(chan string)
+func Filter(ch <-chan logline.Line, filter string) <-chan logline.Line {
+	return FilterFunc(ch, func(line logline.Line) bool {
+		return strings.Contains(line.Raw, filter)
+	})
+}
+
+// FilterFunc forwards the lines for which keep returns true. The others
+// are done with, so their checkpoint is committed and a restart does not
+// read them again.
+func FilterFunc(ch <-chan logline.Line, keep func(logline.Line) bool) <-chan logline.Line {
+	out := make(chan logline.Line)
 
 	go func() {
 		defer close(out)
 		for f := range ch {
-			if strings.Contains(f, filter) {
+			if keep(f) {
 				out <- f
+			} else {
+				f.Checkpoint.Commit()
 			}
 		}
 	}()
@@ -17,3 +33,31 @@ func Filter(ch <-chan string, filter string) <-chan string {
 	return out
 
 }
+
+// Between forwards the lines of a single source whose time falls within
+// [since, until); a zero bound leaves that side open. Lines without a time,
+// such as stack trace frames, follow the decision made for the line before
+// them, and lines before the first timestamp are kept.
+func Between(ch <-chan logline.Line, since, until time.Time) <-chan logline.Line {
+	keep := true
+	return FilterFunc(ch, func(l logline.Line) bool {
+		if !l.Time.IsZero() {
+			keep = (since.IsZero() || !l.Time.Before(since)) && (until.IsZero() || l.Time.Before(until))
+		}
+		return keep
+	})
+}
+
+// AtLeast forwards the lines of a single source whose level is min or more
+// severe. Lines without a recognised level, such as stack trace frames,
+// follow the decision made for the line before them, and lines before the
+// first level are dropped.
+func AtLeast(ch <-chan logline.Line, min level.Level) <-chan logline.Line {
+	keep := false
+	return FilterFunc(ch, func(l logline.Line) bool {
+		if lv, ok := level.Of(l); ok {
+			keep = lv >= min
+		}
+		return keep
+	})
+}
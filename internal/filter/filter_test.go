@@ -1,30 +1,41 @@
 package filter
 
 import (
+	"context"
+	"logagg/internal/checkpoint"
+	"logagg/internal/level"
+	"logagg/internal/logline"
+	"logagg/internal/reader"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
 
+func line(source, text string) logline.Line {
+	return logline.Line{Source: source, Raw: text}
+}
+
 func TestFilter_WithMatch(t *testing.T) {
 	// Create input channel
-	input := make(chan string)
+	input := make(chan logline.Line)
 
 	// Start filter
 	output := Filter(input, "ERROR")
 
 	// Send messages
 	go func() {
-		input <- "[app.log] - This is an ERROR message"
-		input <- "[app.log] - This is an INFO message"
-		input <- "[app.log] - Another ERROR occurred"
-		input <- "[app.log] - DEBUG information"
+		input <- line("app.log", "This is an ERROR message")
+		input <- line("app.log", "This is an INFO message")
+		input <- line("app.log", "Another ERROR occurred")
+		input <- line("app.log", "DEBUG information")
 		close(input)
 	}()
 
 	// Collect filtered messages
 	var messages []string
 	for msg := range output {
-		messages = append(messages, msg)
+		messages = append(messages, msg.Raw)
 	}
 
 	// Should only receive ERROR messages
@@ -40,14 +51,14 @@ func TestFilter_WithMatch(t *testing.T) {
 }
 
 func TestFilter_NoMatch(t *testing.T) {
-	input := make(chan string)
+	input := make(chan logline.Line)
 
 	output := Filter(input, "CRITICAL")
 
 	go func() {
-		input <- "[app.log] - This is an ERROR message"
-		input <- "[app.log] - This is an INFO message"
-		input <- "[app.log] - DEBUG information"
+		input <- line("app.log", "This is an ERROR message")
+		input <- line("app.log", "This is an INFO message")
+		input <- line("app.log", "DEBUG information")
 		close(input)
 	}()
 
@@ -57,7 +68,7 @@ func TestFilter_NoMatch(t *testing.T) {
 
 	go func() {
 		for msg := range output {
-			messages = append(messages, msg)
+			messages = append(messages, msg.Raw)
 		}
 		done <- true
 	}()
@@ -73,21 +84,21 @@ func TestFilter_NoMatch(t *testing.T) {
 }
 
 func TestFilter_EmptyFilter(t *testing.T) {
-	input := make(chan string)
+	input := make(chan logline.Line)
 
 	// Empty filter should match all strings (as all strings contain empty string)
 	output := Filter(input, "")
 
 	go func() {
-		input <- "[app.log] - Message 1"
-		input <- "[app.log] - Message 2"
-		input <- "[app.log] - Message 3"
+		input <- line("app.log", "Message 1")
+		input <- line("app.log", "Message 2")
+		input <- line("app.log", "Message 3")
 		close(input)
 	}()
 
 	var messages []string
 	for msg := range output {
-		messages = append(messages, msg)
+		messages = append(messages, msg.Raw)
 	}
 
 	// Empty string is contained in all strings, so all should pass
@@ -97,14 +108,14 @@ func TestFilter_EmptyFilter(t *testing.T) {
 }
 
 func TestFilter_EmptyInput(t *testing.T) {
-	input := make(chan string)
+	input := make(chan logline.Line)
 	close(input)
 
 	output := Filter(input, "ERROR")
 
 	var messages []string
 	for msg := range output {
-		messages = append(messages, msg)
+		messages = append(messages, msg.Raw)
 	}
 
 	if len(messages) != 0 {
@@ -113,20 +124,20 @@ func TestFilter_EmptyInput(t *testing.T) {
 }
 
 func TestFilter_CaseSensitive(t *testing.T) {
-	input := make(chan string)
+	input := make(chan logline.Line)
 
 	output := Filter(input, "error")
 
 	go func() {
-		input <- "[app.log] - This is an ERROR message"
-		input <- "[app.log] - This is an error message"
-		input <- "[app.log] - Error occurred"
+		input <- line("app.log", "This is an ERROR message")
+		input <- line("app.log", "This is an error message")
+		input <- line("app.log", "Error occurred")
 		close(input)
 	}()
 
 	var messages []string
 	for msg := range output {
-		messages = append(messages, msg)
+		messages = append(messages, msg.Raw)
 	}
 
 	// Should only match lowercase "error"
@@ -140,20 +151,20 @@ func TestFilter_CaseSensitive(t *testing.T) {
 }
 
 func TestFilter_PartialMatch(t *testing.T) {
-	input := make(chan string)
+	input := make(chan logline.Line)
 
 	output := Filter(input, "ERR")
 
 	go func() {
-		input <- "[app.log] - ERROR message"
-		input <- "[app.log] - WARNING message"
-		input <- "[app.log] - ERRNO 404"
+		input <- line("app.log", "ERROR message")
+		input <- line("app.log", "WARNING message")
+		input <- line("app.log", "ERRNO 404")
 		close(input)
 	}()
 
 	var messages []string
 	for msg := range output {
-		messages = append(messages, msg)
+		messages = append(messages, msg.Raw)
 	}
 
 	// Should match both ERROR and ERRNO (both contain "ERR")
@@ -163,20 +174,20 @@ func TestFilter_PartialMatch(t *testing.T) {
 }
 
 func TestFilter_SpecialCharacters(t *testing.T) {
-	input := make(chan string)
+	input := make(chan logline.Line)
 
 	output := Filter(input, "[ERROR]")
 
 	go func() {
-		input <- "[app.log] - [ERROR] Something went wrong"
-		input <- "[app.log] - ERROR: issue detected"
-		input <- "[app.log] - [ERROR] Another problem"
+		input <- line("app.log", "[ERROR] Something went wrong")
+		input <- line("app.log", "ERROR: issue detected")
+		input <- line("app.log", "[ERROR] Another problem")
 		close(input)
 	}()
 
 	var messages []string
 	for msg := range output {
-		messages = append(messages, msg)
+		messages = append(messages, msg.Raw)
 	}
 
 	// Should only match exact pattern "[ERROR]"
@@ -192,30 +203,31 @@ func TestFilter_SpecialCharacters(t *testing.T) {
 }
 
 func TestFilter_MultipleOccurrences(t *testing.T) {
-	input := make(chan string)
+	input := make(chan logline.Line)
 
 	output := Filter(input, "log")
 
 	go func() {
-		input <- "[app.log] - Logging information to log file"
-		input <- "[system.log] - System message"
-		input <- "[debug.txt] - Debug info"
+		input <- line("app.log", "Logging information to log file")
+		input <- line("system.log", "System message")
+		input <- line("debug.txt", "Debug info")
 		close(input)
 	}()
 
 	var messages []string
 	for msg := range output {
-		messages = append(messages, msg)
+		messages = append(messages, msg.Raw)
 	}
 
-	// First two messages contain "log"
-	if len(messages) != 2 {
-		t.Errorf("expected 2 messages containing 'log', got %d", len(messages))
+	// Only the text is searched, so the "log" in the source names does not
+	// count
+	if len(messages) != 1 {
+		t.Errorf("expected 1 message containing 'log', got %d", len(messages))
 	}
 }
 
 func TestFilter_LargeVolume(t *testing.T) {
-	input := make(chan string, 1000)
+	input := make(chan logline.Line, 1000)
 
 	output := Filter(input, "match")
 
@@ -223,9 +235,9 @@ func TestFilter_LargeVolume(t *testing.T) {
 	go func() {
 		for i := 0; i < 1000; i++ {
 			if i%10 == 0 {
-				input <- "message with match"
+				input <- line("app.log", "message with match")
 			} else {
-				input <- "message without"
+				input <- line("app.log", "message without")
 			}
 		}
 		close(input)
@@ -253,13 +265,13 @@ func TestFilter_LargeVolume(t *testing.T) {
 }
 
 func TestFilter_ChannelClosure(t *testing.T) {
-	input := make(chan string)
+	input := make(chan logline.Line)
 
 	output := Filter(input, "test")
 
 	go func() {
-		input <- "test message 1"
-		input <- "test message 2"
+		input <- line("app.log", "test message 1")
+		input <- line("app.log", "test message 2")
 		close(input)
 	}()
 
@@ -299,3 +311,122 @@ func stringContains(s, substr string) bool {
 	}
 	return false
 }
+
+func TestBetween(t *testing.T) {
+	at := func(text string, minute int) logline.Line {
+		l := line("app.log", text)
+		if minute >= 0 {
+			l.Time = time.Date(2024, 1, 15, 10, minute, 0, 0, time.UTC)
+		}
+		return l
+	}
+
+	input := make(chan logline.Line, 8)
+	input <- at("banner", -1)
+	input <- at("too early", 0)
+	input <- at("  early frame", -1)
+	input <- at("start", 5)
+	input <- at("  frame", -1)
+	input <- at("middle", 7)
+	input <- at("at until", 10)
+	input <- at("  late frame", -1)
+	close(input)
+
+	since := time.Date(2024, 1, 15, 10, 5, 0, 0, time.UTC)
+	until := time.Date(2024, 1, 15, 10, 10, 0, 0, time.UTC)
+
+	var got []string
+	for l := range Between(input, since, until) {
+		got = append(got, l.Raw)
+	}
+
+	want := []string{"banner", "start", "  frame", "middle"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+}
+
+func TestBetween_OpenBounds(t *testing.T) {
+	input := make(chan logline.Line, 1)
+	input <- logline.Line{Raw: "x", Time: time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC)}
+	close(input)
+
+	count := 0
+	for range Between(input, time.Time{}, time.Time{}) {
+		count++
+	}
+	if count != 1 {
+		t.Errorf("expected the line to pass without bounds, got %d lines", count)
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	input := make(chan logline.Line, 10)
+	input <- line("app.log", "starting up")
+	input <- line("app.log", "10:00:01 INFO listening")
+	input <- line("app.log", "10:00:02 WARN slow query")
+	input <- line("app.log", "10:00:03 [error] NoERRORs here is still an error")
+	input <- line("app.log", "  at Foo.bar(Foo.java:10)")
+	input <- logline.Line{Source: "api.log", Raw: `{"level":"DEBUG"}`, Level: "DEBUG"}
+	input <- logline.Line{Source: "api.log", Raw: `{"level":"crit"}`, Level: "crit"}
+	input <- line("app.log", "E0115 10:00:04.000000 1 main.go:10] boom")
+	input <- logline.Line{Source: "pino.log", Raw: `{"level":30,"msg":"ok"}`, Level: "30"}
+	input <- logline.Line{Source: "pino.log", Raw: `{"level":50,"msg":"failed"}`, Level: "50"}
+	close(input)
+
+	var got []string
+	for l := range AtLeast(input, level.Warn) {
+		got = append(got, l.Raw)
+	}
+
+	want := []string{
+		"10:00:02 WARN slow query",
+		"10:00:03 [error] NoERRORs here is still an error",
+		"  at Foo.bar(Foo.java:10)",
+		`{"level":"crit"}`,
+		"E0115 10:00:04.000000 1 main.go:10] boom",
+		`{"level":50,"msg":"failed"}`,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+}
+
+func TestFilterFunc_CommitsDroppedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("one\ntwo\nthree\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store := checkpoint.NewMemory()
+	read := func() []logline.Line {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		var got []logline.Line
+		for l := range FilterFunc(reader.Read(ctx, path, reader.Options{Checkpoints: store}), func(logline.Line) bool { return false }) {
+			got = append(got, l)
+		}
+		return got
+	}
+
+	if got := read(); len(got) != 0 {
+		t.Fatalf("expected every line to be dropped, got %d", len(got))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, e, ok := store.Lookup(f); !ok || e.Offset != 14 || e.Line != 3 {
+		t.Errorf("expected the checkpoint past the dropped lines, got %+v (found %v)", e, ok)
+	}
+}
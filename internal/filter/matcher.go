@@ -0,0 +1,108 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// Options describes which lines a Matcher keeps. Patterns use RE2 syntax.
+type Options struct {
+	// Include lists patterns a line must match; an empty list keeps every
+	// line not excluded.
+	Include []string
+	// Exclude lists patterns that drop a line when any of them matches.
+	Exclude []string
+	// Any keeps a line when at least one include pattern matches instead of
+	// requiring all of them.
+	Any bool
+	// IgnoreCase makes every pattern case-insensitive.
+	IgnoreCase bool
+}
+
+type Matcher struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+	any     bool
+}
+
+func Compile(opts Options) (*Matcher, error) {
+	include, err := compileAll(opts.Include, opts.IgnoreCase)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := compileAll(opts.Exclude, opts.IgnoreCase)
+	if err != nil {
+		return nil, err
+	}
+	return &Matcher{include: include, exclude: exclude, any: opts.Any}, nil
+}
+
+func compileAll(patterns []string, ignoreCase bool) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		expr := p
+		if ignoreCase {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("padrão inválido %q: %w", p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+func (m *Matcher) Match(line string) bool {
+	for _, re := range m.exclude {
+		if re.MatchString(line) {
+			return false
+		}
+	}
+	if len(m.include) == 0 {
+		return true
+	}
+	if m.any {
+		for _, re := range m.include {
+			if re.MatchString(line) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, re := range m.include {
+		if !re.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// Highlights returns the byte ranges of line matched by the include
+// patterns, sorted and with overlapping ranges merged, so they can be
+// emphasised when the line is displayed.
+func (m *Matcher) Highlights(line string) [][2]int {
+	var spans [][2]int
+	for _, re := range m.include {
+		for _, loc := range re.FindAllStringIndex(line, -1) {
+			if loc[0] < loc[1] {
+				spans = append(spans, [2]int{loc[0], loc[1]})
+			}
+		}
+	}
+	if len(spans) < 2 {
+		return spans
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+	merged := spans[:1]
+	for _, s := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if s[0] <= last[1] {
+			last[1] = max(last[1], s[1])
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
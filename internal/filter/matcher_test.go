@@ -0,0 +1,144 @@
+package filter
+
+import (
+	"logagg/internal/logline"
+	"reflect"
+	"testing"
+)
+
+func TestMatcher_Match(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		line string
+		want bool
+	}{
+		{
+			name: "no patterns keeps everything",
+			opts: Options{},
+			line: "INFO started",
+			want: true,
+		},
+		{
+			name: "regex include",
+			opts: Options{Include: []string{`ERROR|FATAL`}},
+			line: "FATAL out of memory",
+			want: true,
+		},
+		{
+			name: "all patterns must match",
+			opts: Options{Include: []string{"ERROR", "payments"}},
+			line: "ERROR orders timeout",
+			want: false,
+		},
+		{
+			name: "any pattern may match",
+			opts: Options{Include: []string{"ERROR", "payments"}, Any: true},
+			line: "ERROR orders timeout",
+			want: true,
+		},
+		{
+			name: "any with no match",
+			opts: Options{Include: []string{"WARN", "payments"}, Any: true},
+			line: "ERROR orders timeout",
+			want: false,
+		},
+		{
+			name: "exclude wins over include",
+			opts: Options{Include: []string{"ERROR"}, Exclude: []string{"HealthCheck"}},
+			line: "ERROR HealthCheck failed",
+			want: false,
+		},
+		{
+			name: "exclude only",
+			opts: Options{Exclude: []string{"DEBUG"}},
+			line: "INFO ok",
+			want: true,
+		},
+		{
+			name: "case sensitive by default",
+			opts: Options{Include: []string{"error"}},
+			line: "ERROR boom",
+			want: false,
+		},
+		{
+			name: "ignore case",
+			opts: Options{Include: []string{"error"}, Exclude: []string{"healthcheck"}, IgnoreCase: true},
+			line: "ERROR boom",
+			want: true,
+		},
+		{
+			name: "ignore case applies to excludes",
+			opts: Options{Exclude: []string{"healthcheck"}, IgnoreCase: true},
+			line: "ERROR HealthCheck failed",
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Compile(tt.opts)
+			if err != nil {
+				t.Fatalf("Compile() unexpected error = %v", err)
+			}
+			if got := m.Match(tt.line); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile_InvalidPattern(t *testing.T) {
+	_, err := Compile(Options{Include: []string{"ERROR("}})
+	if err == nil {
+		t.Fatal("Compile() error = nil, want error for invalid regex")
+	}
+	if !contains(err.Error(), "padrão inválido") {
+		t.Errorf("Compile() error = %v, want error containing %q", err, "padrão inválido")
+	}
+}
+
+func TestFilterFunc(t *testing.T) {
+	m, err := Compile(Options{Include: []string{`^INFO`}, Exclude: []string{"DEBUG"}})
+	if err != nil {
+		t.Fatalf("Compile() unexpected error = %v", err)
+	}
+
+	input := make(chan logline.Line)
+	output := FilterFunc(input, func(l logline.Line) bool {
+		return l.Name() == "app.log" && m.Match(l.Raw)
+	})
+
+	go func() {
+		input <- line("app.log", "INFO kept")
+		input <- line("app.log", "DEBUG dropped")
+		input <- line("db.log", "INFO dropped")
+		close(input)
+	}()
+
+	var messages []string
+	for msg := range output {
+		messages = append(messages, msg.Raw)
+	}
+
+	if len(messages) != 1 || messages[0] != "INFO kept" {
+		t.Errorf("expected only the app INFO line, got %v", messages)
+	}
+}
+
+func TestMatcher_Highlights(t *testing.T) {
+	m, err := Compile(Options{Include: []string{"time", "out|timeout"}, Exclude: []string{"retry"}, IgnoreCase: true})
+	if err != nil {
+		t.Fatalf("Compile() unexpected error = %v", err)
+	}
+
+	got := m.Highlights("Timeout after TIME limit, out")
+	want := [][2]int{{0, 7}, {14, 18}, {26, 29}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Highlights() = %v, want %v", got, want)
+	}
+
+	if got := m.Highlights("nothing here"); len(got) != 0 {
+		t.Errorf("Highlights() = %v, want none", got)
+	}
+}
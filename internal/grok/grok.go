@@ -0,0 +1,171 @@
+// Package grok compiles Logstash style grok expressions, such as
+// "%{IP:client} %{WORD:method} %{URIPATHPARAM:path}", into regular
+// expressions whose matches become named fields.
+package grok
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Library holds named patterns that expressions refer to as %{NAME}.
+type Library struct {
+	patterns map[string]string
+}
+
+// NewLibrary returns a library with the built-in patterns: numbers, IP
+// addresses and host names, paths and URIs, UUIDs, dates and times, and log
+// levels.
+func NewLibrary() *Library {
+	l := &Library{patterns: make(map[string]string, len(builtin))}
+	for name, p := range builtin {
+		l.patterns[name] = p
+	}
+	return l
+}
+
+// Add defines or replaces a pattern.
+func (l *Library) Add(name, pattern string) {
+	l.patterns[name] = pattern
+}
+
+// LoadFile adds the patterns of a file in the Logstash format: one
+// "NAME pattern" per line, with blank lines and lines starting with #
+// ignored.
+func (l *Library) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, pattern, ok := strings.Cut(line, " ")
+		pattern = strings.TrimSpace(pattern)
+		if !ok || pattern == "" || !patternName.MatchString(name) {
+			return fmt.Errorf("arquivo de padrões %s, linha %d: esperado \"NOME padrão\"", path, n)
+		}
+		l.Add(name, pattern)
+	}
+	return sc.Err()
+}
+
+var (
+	patternName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	reference   = regexp.MustCompile(`%\{([A-Za-z0-9_]+)(?::([^:}]+))?(?::(int|float))?\}`)
+)
+
+// Grok is a compiled expression.
+type Grok struct {
+	re     *regexp.Regexp
+	fields []field
+}
+
+type field struct {
+	name  string
+	group int
+	conv  string
+}
+
+// Compile expands the pattern references of expr. %{NAME} matches the
+// pattern NAME, %{NAME:field} also records the text it matched as field, and
+// %{NAME:field:int} or %{NAME:field:float} records it as a number.
+func (l *Library) Compile(expr string) (*Grok, error) {
+	g := &Grok{}
+	src, err := l.expand(expr, g, nil)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(src)
+	if err != nil {
+		return nil, fmt.Errorf("expressão grok inválida %q: %w", expr, err)
+	}
+	g.re = re
+
+	// Groups were numbered as they were written; map them to the group
+	// indexes of the compiled expression.
+	for i := range g.fields {
+		g.fields[i].group = re.SubexpIndex("g" + strconv.Itoa(g.fields[i].group))
+	}
+	return g, nil
+}
+
+// expand replaces the references in expr, recursively. stack holds the
+// patterns being expanded, to detect cycles.
+func (l *Library) expand(expr string, g *Grok, stack []string) (string, error) {
+	var err error
+	out := reference.ReplaceAllStringFunc(expr, func(ref string) string {
+		if err != nil {
+			return ""
+		}
+		m := reference.FindStringSubmatch(ref)
+		name, fieldName, conv := m[1], m[2], m[3]
+
+		pattern, ok := l.patterns[name]
+		if !ok {
+			err = fmt.Errorf("padrão grok desconhecido %%{%s}", name)
+			return ""
+		}
+		for _, s := range stack {
+			if s == name {
+				err = fmt.Errorf("padrão grok recursivo %%{%s}", name)
+				return ""
+			}
+		}
+
+		var inner string
+		if inner, err = l.expand(pattern, g, append(stack, name)); err != nil {
+			return ""
+		}
+		if fieldName == "" {
+			return "(?:" + inner + ")"
+		}
+		id := len(g.fields)
+		g.fields = append(g.fields, field{name: fieldName, group: id, conv: conv})
+		return "(?P<g" + strconv.Itoa(id) + ">" + inner + ")"
+	})
+	return out, err
+}
+
+// Match returns the fields recorded by the expression when it matches s.
+// Like Logstash, the expression is not anchored; start it with ^ to match
+// from the beginning of the line. Fields whose pattern did not take part in
+// the match are left out.
+func (g *Grok) Match(s string) (map[string]any, bool) {
+	m := g.re.FindStringSubmatchIndex(s)
+	if m == nil {
+		return nil, false
+	}
+
+	fields := make(map[string]any, len(g.fields))
+	for _, f := range g.fields {
+		start, end := m[2*f.group], m[2*f.group+1]
+		if start < 0 {
+			continue
+		}
+		v := s[start:end]
+		switch f.conv {
+		case "int":
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				fields[f.name] = n
+				continue
+			}
+		case "float":
+			if n, err := strconv.ParseFloat(v, 64); err == nil {
+				fields[f.name] = n
+				continue
+			}
+		}
+		fields[f.name] = v
+	}
+	return fields, true
+}
@@ -0,0 +1,156 @@
+package grok
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompile_Match(t *testing.T) {
+	g, err := NewLibrary().Compile(`%{TIMESTAMP_ISO8601:ts} %{LOGLEVEL:level} \[%{DATA:thread}\] %{GREEDYDATA:msg}`)
+	if err != nil {
+		t.Fatalf("Compile() unexpected error = %v", err)
+	}
+
+	fields, ok := g.Match("2024-03-01 10:00:00,123 ERROR [pool-1-thread-3] Payment declined")
+	if !ok {
+		t.Fatal("Match() = false, want true")
+	}
+	want := map[string]any{
+		"ts":     "2024-03-01 10:00:00,123",
+		"level":  "ERROR",
+		"thread": "pool-1-thread-3",
+		"msg":    "Payment declined",
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("Match() = %v, want %v", fields, want)
+	}
+
+	if _, ok := g.Match("no timestamp here"); ok {
+		t.Error("Match() on a non matching line = true, want false")
+	}
+}
+
+func TestBuiltinPatterns(t *testing.T) {
+	tests := []struct {
+		pattern string
+		match   []string
+		reject  []string
+	}{
+		{"IPV4", []string{"192.168.0.1", "8.8.8.8"}, []string{"256.1.1.1", "1.2.3"}},
+		{"IP", []string{"10.0.0.1", "2001:db8::1", "::1", "fe80:0:0:0:0:0:0:1"}, []string{"host"}},
+		{"UUID", []string{"123e4567-e89b-12d3-a456-426614174000"}, []string{"123e4567-e89b-12d3-a456"}},
+		{"NUMBER", []string{"42", "-3.14", ".5"}, []string{"abc"}},
+		{"INT", []string{"-7", "+12"}, []string{"1.5"}},
+		{"LOGLEVEL", []string{"INFO", "warning", "ERR", "Fatal", "DEBUG"}, []string{"verbose"}},
+		{"UNIXPATH", []string{"/var/log/app.log"}, []string{"relative/path"}},
+		{"PATH", []string{`C:\logs\app.log`, "/tmp/x"}, []string{"x"}},
+		{"HOSTNAME", []string{"api-1.example.com"}, []string{"-bad"}},
+		{"TIMESTAMP_ISO8601", []string{"2024-03-01T10:00:00Z", "2024-03-01 10:00:00.5+02:00"}, []string{"2024/03/01"}},
+		{"SYSLOGTIMESTAMP", []string{"Mar  1 10:00:00"}, []string{"1 Mar 10:00"}},
+		{"HTTPDATE", []string{"10/Oct/2023:13:55:36 -0700"}, []string{"2023-10-10"}},
+		{"URI", []string{"https://user@example.com:8443/a/b?c=d"}, []string{"example.com"}},
+		{"EMAILADDRESS", []string{"ops@example.com"}, []string{"ops.example.com"}},
+	}
+
+	lib := NewLibrary()
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			g, err := lib.Compile("^%{" + tt.pattern + "}$")
+			if err != nil {
+				t.Fatalf("Compile() unexpected error = %v", err)
+			}
+			for _, s := range tt.match {
+				if _, ok := g.Match(s); !ok {
+					t.Errorf("%s should match %q", tt.pattern, s)
+				}
+			}
+			for _, s := range tt.reject {
+				if _, ok := g.Match(s); ok {
+					t.Errorf("%s should not match %q", tt.pattern, s)
+				}
+			}
+		})
+	}
+}
+
+func TestCompile_Conversions(t *testing.T) {
+	g, err := NewLibrary().Compile(`status=%{INT:http.status:int} dur=%{NUMBER:dur:float} id=%{WORD:id:int}`)
+	if err != nil {
+		t.Fatalf("Compile() unexpected error = %v", err)
+	}
+
+	fields, ok := g.Match("status=503 dur=0.25 id=abc")
+	if !ok {
+		t.Fatal("Match() = false, want true")
+	}
+	want := map[string]any{"http.status": int64(503), "dur": 0.25, "id": "abc"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("Match() = %#v, want %#v", fields, want)
+	}
+}
+
+func TestCompile_OptionalGroups(t *testing.T) {
+	g, err := NewLibrary().Compile(`^%{WORD:verb}(?: %{INT:code})?$`)
+	if err != nil {
+		t.Fatalf("Compile() unexpected error = %v", err)
+	}
+	fields, ok := g.Match("ping")
+	if !ok {
+		t.Fatal("Match() = false, want true")
+	}
+	if _, ok := fields["code"]; ok {
+		t.Errorf("expected unmatched optional field to be left out, got %v", fields)
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	lib := NewLibrary()
+	lib.Add("LOOP_A", "%{LOOP_B}")
+	lib.Add("LOOP_B", "x%{LOOP_A}")
+
+	tests := []struct {
+		expr string
+		msg  string
+	}{
+		{"%{NOPE:x}", "desconhecido"},
+		{"%{LOOP_A}", "recursivo"},
+		{"%{WORD:x} (", "inválida"},
+	}
+	for _, tt := range tests {
+		if _, err := lib.Compile(tt.expr); err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("Compile(%q) error = %v, want error containing %q", tt.expr, err, tt.msg)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "patterns")
+	content := "# legacy billing app\n\nBILLING_ID BIL-%{INT}\nTHREAD \\[[^\\]]+\\]\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	lib := NewLibrary()
+	if err := lib.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile() unexpected error = %v", err)
+	}
+	g, err := lib.Compile(`%{THREAD:thread} %{BILLING_ID:invoice}`)
+	if err != nil {
+		t.Fatalf("Compile() unexpected error = %v", err)
+	}
+	fields, ok := g.Match("[worker-2] BIL-0042 paid")
+	if !ok || fields["invoice"] != "BIL-0042" || fields["thread"] != "[worker-2]" {
+		t.Errorf("Match() = %v, %v", fields, ok)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad")
+	if err := os.WriteFile(bad, []byte("ONLYNAME\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := lib.LoadFile(bad); err == nil || !strings.Contains(err.Error(), "linha 1") {
+		t.Errorf("LoadFile() error = %v, want error pointing at line 1", err)
+	}
+}
@@ -0,0 +1,163 @@
+package level
+
+import (
+	"context"
+	"fmt"
+	"logagg/internal/logline"
+	"regexp"
+	"strings"
+)
+
+// Level is a normalised severity. The zero value means unknown; known
+// levels compare in order of severity.
+type Level int
+
+const (
+	Unknown Level = iota
+	Trace
+	Debug
+	Info
+	Warn
+	Error
+	Fatal
+)
+
+var names = []string{"", "trace", "debug", "info", "warn", "error", "fatal"}
+
+func (l Level) String() string {
+	if l < 0 || int(l) >= len(names) {
+		return ""
+	}
+	return names[l]
+}
+
+// aliases maps the lower-cased spellings used by common loggers onto a
+// level.
+var aliases = map[string]Level{
+	"trace": Trace, "trc": Trace, "finest": Trace, "finer": Trace, "verbose": Trace,
+	"debug": Debug, "dbg": Debug, "fine": Debug,
+	"info": Info, "inf": Info, "information": Info, "informational": Info, "notice": Info,
+	"warn": Warn, "wrn": Warn, "warning": Warn,
+	"error": Error, "err": Error, "eror": Error, "severe": Error,
+	"fatal": Fatal, "ftl": Fatal, "crit": Fatal, "critical": Fatal, "alert": Fatal, "emerg": Fatal, "emergency": Fatal, "panic": Fatal,
+}
+
+// numeric maps the numbers pino and bunyan write as the level of JSON
+// lines onto a level.
+var numeric = map[string]Level{"10": Trace, "20": Debug, "30": Info, "40": Warn, "50": Error, "60": Fatal}
+
+// Parse normalises a level name such as "WARNING", "err" or "Critical", or
+// a pino or bunyan level number such as 30 or 50.
+func Parse(s string) (Level, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if l, ok := numeric[s]; ok {
+		return l, true
+	}
+	l, ok := aliases[s]
+	return l, ok
+}
+
+// ParseThreshold parses the value of --level, which only accepts the
+// normalised names.
+func ParseThreshold(s string) (Level, error) {
+	for i, n := range names {
+		if i > 0 && n == strings.ToLower(s) {
+			return Level(i), nil
+		}
+	}
+	return Unknown, fmt.Errorf("nível inválido %q (use %s)", s, strings.Join(names[1:], ", "))
+}
+
+// glog matches the prefix of glog and klog lines, as in
+// "E0115 10:23:45.123456 ...".
+var glog = regexp.MustCompile(`^([IWEF])\d{4} \d{2}:\d{2}:\d{2}`)
+
+var glogLevels = map[byte]Level{'I': Info, 'W': Warn, 'E': Error, 'F': Fatal}
+
+// searchWords is how many words at the start of a line are looked at for a
+// level; a level word further in is most likely part of the message.
+const searchWords = 12
+
+// Detect finds the level written in a free-form line. A level is a whole
+// word that is either all upper case ("ERROR", "WRN") or, in any case,
+// enclosed in brackets ("[warn]", "<Info>"), within the first words of the
+// line; glog prefixes are recognised as well.
+func Detect(raw string) (Level, bool) {
+	if m := glog.FindStringSubmatch(raw); m != nil {
+		return glogLevels[m[1][0]], true
+	}
+
+	words := 0
+	for i := 0; i < len(raw) && words < searchWords; {
+		if !isWord(raw[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(raw) && isWord(raw[i]) {
+			i++
+		}
+		words++
+		word := raw[start:i]
+		l, ok := aliases[strings.ToLower(word)]
+		if !ok {
+			continue
+		}
+		if word == strings.ToUpper(word) || bracketed(raw, start, i) {
+			return l, true
+		}
+	}
+	return Unknown, false
+}
+
+func isWord(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
+
+func bracketed(raw string, start, end int) bool {
+	if start == 0 || end == len(raw) {
+		return false
+	}
+	switch raw[start-1] {
+	case '[':
+		return raw[end] == ']'
+	case '<':
+		return raw[end] == '>'
+	case '(':
+		return raw[end] == ')'
+	}
+	return false
+}
+
+// Of returns the level of l: the one set by its parser, normalised, or else
+// the one detected in its text, which is also where a level the parser
+// set but Parse does not know is looked for.
+func Of(l logline.Line) (Level, bool) {
+	if lv, ok := Parse(l.Level); ok {
+		return lv, true
+	}
+	return Detect(l.Raw)
+}
+
+// Stamp normalises the level of every line received from in, detecting it
+// from the text when the parser did not set one. Levels that are not
+// recognised are left as written.
+func Stamp(ctx context.Context, in <-chan logline.Line) <-chan logline.Line {
+	out := make(chan logline.Line)
+
+	go func() {
+		defer close(out)
+		for l := range in {
+			if lv, ok := Of(l); ok {
+				l.Level = lv.String()
+			}
+			select {
+			case out <- l:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
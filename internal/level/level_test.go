@@ -0,0 +1,119 @@
+package level
+
+import (
+	"context"
+	"logagg/internal/logline"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Level
+	}{
+		{"TRACE", Trace},
+		{"dbg", Debug},
+		{"Information", Info},
+		{"notice", Info},
+		{"WARNING", Warn},
+		{"err", Error},
+		{"SEVERE", Error},
+		{"crit", Fatal},
+		{"panic", Fatal},
+		{"10", Trace},
+		{"30", Info},
+		{"40", Warn},
+		{"60", Fatal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ok := Parse(tt.in)
+			if !ok || got != tt.want {
+				t.Errorf("Parse(%q) = %v, %v; want %v", tt.in, got, ok, tt.want)
+			}
+		})
+	}
+
+	for _, s := range []string{"loud", "35"} {
+		if _, ok := Parse(s); ok {
+			t.Errorf("Parse(%q) ok = true, want false", s)
+		}
+	}
+}
+
+func TestParseThreshold(t *testing.T) {
+	if got, err := ParseThreshold("WARN"); err != nil || got != Warn {
+		t.Errorf("ParseThreshold(\"WARN\") = %v, %v", got, err)
+	}
+	if _, err := ParseThreshold("warning"); err == nil {
+		t.Error("ParseThreshold(\"warning\") expected an error")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		line string
+		want Level
+	}{
+		{"2024-01-15 10:23:45,123 ERROR [main] com.example.App - boom", Error},
+		{"2024-01-15T10:23:45Z [WARN] disk almost full", Warn},
+		{"[2024-01-15 10:23:45] production.INFO: user logged in", Info},
+		{"10:23:45 <debug> cache miss", Debug},
+		{"WARNING:root:deprecated call", Warn},
+		{"2024/01/15 10:23:45 ERR connection refused", Error},
+		{"level 10:23:45 (Trace) entering", Trace},
+		{"E0115 10:23:45.123456   42 main.go:10] failed to sync", Error},
+		{"W0115 10:23:45.123456   42 main.go:10] retrying", Warn},
+		{"I0115 10:23:45.123456   42 main.go:10] ready", Info},
+		{"F0115 10:23:45.123456   42 main.go:10] giving up", Fatal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, ok := Detect(tt.line)
+			if !ok || got != tt.want {
+				t.Errorf("Detect() = %v, %v; want %v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetect_NoLevel(t *testing.T) {
+	lines := []string{
+		"NoERRORs were found",
+		"ERROR_CODE=12 returned",
+		"an error occurred while saving",
+		"Info about the build",
+		"E0115 is a part number",
+		"a b c d e f g h i j k l ERROR too far into the message",
+	}
+
+	for _, line := range lines {
+		if got, ok := Detect(line); ok {
+			t.Errorf("Detect(%q) = %v, want no level", line, got)
+		}
+	}
+}
+
+func TestStamp(t *testing.T) {
+	in := make(chan logline.Line, 5)
+	in <- logline.Line{Raw: `{"level":"WARNING"}`, Level: "WARNING"}
+	in <- logline.Line{Raw: "10:23:45 ERROR boom"}
+	in <- logline.Line{Raw: "x", Level: "loud"}
+	in <- logline.Line{Raw: `{"level":50,"msg":"boom"}`, Level: "50"}
+	in <- logline.Line{Raw: `{"severity":"S","msg":"[warn] disk"}`, Level: "S"}
+	close(in)
+
+	var got []string
+	for l := range Stamp(context.Background(), in) {
+		got = append(got, l.Level)
+	}
+
+	want := []string{"warn", "error", "loud", "error", "warn"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d: Level = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package logline
+
+import (
+	"fmt"
+	"logagg/internal/checkpoint"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Line is a single record flowing through the pipeline. Readers fill in
+// where it came from; later stages may add a timestamp and parsed fields.
+// Formatting for display is left to the output stage.
+type Line struct {
+	// Source is the path of the file the line was read from.
+	Source string
+	// Label, when set, names the source for display instead of its file
+	// name.
+	Label string
+	// Raw is the line content without its terminator.
+	Raw string
+	// Offset is the byte offset of the start of the line in the source.
+	Offset int64
+	// Number is the 1-based line number within the source, or 0 when the
+	// reader started in the middle of the file and does not know it.
+	Number int64
+	// Ingested is when the line was read.
+	Ingested time.Time
+	// Time is the event time written in the line, zero when unknown.
+	Time time.Time
+	// TimeText is the time as a parser found it written, when it may lack
+	// a zone. The timestamp stage reads it again in the zone and layout of
+	// the source.
+	TimeText string
+	// Level is the severity written in the line, empty when unknown.
+	Level string
+	// Message is the human readable part of a parsed line.
+	Message string
+	// Fields holds values extracted by a parser, keyed by field name.
+	// Structured formats such as JSON may nest maps inside it.
+	Fields map[string]any
+	// Checkpoint, when set, is the position just past the line in its
+	// file, or past the last line of a multi-line event. The output stage
+	// commits it once the line is written.
+	Checkpoint *checkpoint.Position
+}
+
+// Name returns the label of the source or else its base name, which is how
+// lines are labelled for display.
+func (l Line) Name() string {
+	if l.Label != "" {
+		return l.Label
+	}
+	return filepath.Base(l.Source)
+}
+
+// Text returns the raw content of the line.
+func (l Line) Text() string {
+	return l.Raw
+}
+
+// Field returns the value of a named field as text. A dotted name such as
+// http.status reaches into nested fields. Besides parsed fields it knows
+// source (label or file name), path, line, offset, level and msg, which
+// falls back to the raw text when no parser set a message. The level, once
+// set, wins over a parsed field of that name, so it is seen normalised.
+func (l Line) Field(name string) (string, bool) {
+	if name == "level" && l.Level != "" {
+		return l.Level, true
+	}
+	if v, ok := l.Fields[name]; ok {
+		return fmt.Sprint(v), true
+	}
+	if v, ok := nested(l.Fields, name); ok {
+		return fmt.Sprint(v), true
+	}
+
+	switch name {
+	case "source":
+		return l.Name(), true
+	case "path":
+		return l.Source, true
+	case "line":
+		return strconv.FormatInt(l.Number, 10), true
+	case "offset":
+		return strconv.FormatInt(l.Offset, 10), true
+	case "level":
+		return l.Level, l.Level != ""
+	case "msg":
+		if l.Message != "" {
+			return l.Message, true
+		}
+		return l.Raw, true
+	}
+	return "", false
+}
+
+// nested follows a dotted path through maps.
+func nested(fields map[string]any, name string) (any, bool) {
+	key, rest, ok := strings.Cut(name, ".")
+	if !ok {
+		return nil, false
+	}
+	v, ok := fields[key]
+	if !ok {
+		return nil, false
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	if v, ok := m[rest]; ok {
+		return v, true
+	}
+	return nested(m, rest)
+}
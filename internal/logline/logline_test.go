@@ -0,0 +1,110 @@
+package logline
+
+import "testing"
+
+func TestLine_Field(t *testing.T) {
+	l := Line{
+		Source: "/var/log/app/app.log",
+		Raw:    "ERROR boom",
+		Offset: 120,
+		Number: 7,
+		Fields: map[string]any{"status": 500},
+	}
+
+	tests := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{"source", "app.log", true},
+		{"path", "/var/log/app/app.log", true},
+		{"line", "7", true},
+		{"offset", "120", true},
+		{"msg", "ERROR boom", true},
+		{"status", "500", true},
+		{"missing", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := l.Field(tt.name)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("Field(%q) = %q, %v, want %q, %v", tt.name, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLine_Label(t *testing.T) {
+	l := Line{Source: "/var/log/api/2024-01-15.log", Label: "api"}
+
+	if got := l.Name(); got != "api" {
+		t.Errorf("Name() = %q, want %q", got, "api")
+	}
+	if got, _ := l.Field("source"); got != "api" {
+		t.Errorf("Field(\"source\") = %q, want %q", got, "api")
+	}
+	if got, _ := l.Field("path"); got != l.Source {
+		t.Errorf("Field(\"path\") = %q, want %q", got, l.Source)
+	}
+}
+
+func TestLine_ParsedFieldsTakePrecedence(t *testing.T) {
+	l := Line{Source: "app.log", Raw: `{"msg":"hello"}`, Fields: map[string]any{"msg": "hello"}}
+
+	if got, _ := l.Field("msg"); got != "hello" {
+		t.Errorf("Field(msg) = %q, want %q", got, "hello")
+	}
+	if l.Text() != `{"msg":"hello"}` {
+		t.Errorf("Text() = %q, want raw line", l.Text())
+	}
+}
+
+func TestLine_NestedField(t *testing.T) {
+	l := Line{
+		Raw: `{"http":{"status":503,"request":{"method":"GET"}},"k8s.pod":"api-1"}`,
+		Fields: map[string]any{
+			"http":    map[string]any{"status": 503, "request": map[string]any{"method": "GET"}},
+			"k8s.pod": "api-1",
+		},
+	}
+
+	tests := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{"http.status", "503", true},
+		{"http.request.method", "GET", true},
+		{"k8s.pod", "api-1", true},
+		{"http.missing", "", false},
+		{"http.status.code", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := l.Field(tt.name)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("Field(%q) = %q, %v, want %q, %v", tt.name, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLine_LevelAndMessage(t *testing.T) {
+	l := Line{Raw: `{"severity":"WARN","message":"disk"}`, Level: "WARN", Message: "disk"}
+
+	if got, ok := l.Field("level"); !ok || got != "WARN" {
+		t.Errorf("Field(level) = %q, %v, want %q, true", got, ok, "WARN")
+	}
+	if got, _ := l.Field("msg"); got != "disk" {
+		t.Errorf("Field(msg) = %q, want %q", got, "disk")
+	}
+	normalised := Line{Raw: `{"level":"WARNING"}`, Level: "warn", Fields: map[string]any{"level": "WARNING"}}
+	if got, _ := normalised.Field("level"); got != "warn" {
+		t.Errorf("Field(level) = %q, want the normalised %q", got, "warn")
+	}
+	if _, ok := (Line{Raw: "plain"}).Field("level"); ok {
+		t.Error("Field(level) on an unparsed line ok = true, want false")
+	}
+}
@@ -0,0 +1,187 @@
+package multiline
+
+import (
+	"context"
+	"fmt"
+	"logagg/internal/logline"
+	"logagg/internal/timestamp"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Rule describes how physical lines group into logical events, such as a
+// stack trace following the line that logged the exception.
+type Rule struct {
+	// Start is a regular expression matching the first line of an event.
+	Start string
+	// StartTimestamp treats lines beginning with a timestamp in one of
+	// timestamp.Layouts, possibly in brackets, as the first line of an
+	// event.
+	StartTimestamp bool
+	// Continue is a regular expression matching lines that belong to the
+	// previous event. When Start is also set, a line matching Start always
+	// begins a new event.
+	Continue string
+	// MaxLines and MaxBytes cap an event; when a line would exceed them the
+	// event is emitted and the line begins a new one. Zero means no limit.
+	MaxLines int
+	MaxBytes int
+	// Timeout emits a pending event when no line arrives for this long,
+	// so the last event of a tailed file is not held back. Zero waits for
+	// the next line or the end of the input.
+	Timeout time.Duration
+}
+
+// Enabled reports whether the rule groups anything.
+func (r Rule) Enabled() bool {
+	return r.Start != "" || r.StartTimestamp || r.Continue != ""
+}
+
+// Joiner assembles lines into events according to a compiled Rule.
+type Joiner struct {
+	start    []func(string) bool
+	cont     *regexp.Regexp
+	maxLines int
+	maxBytes int
+	timeout  time.Duration
+}
+
+func Compile(r Rule) (*Joiner, error) {
+	j := &Joiner{maxLines: r.MaxLines, maxBytes: r.MaxBytes, timeout: r.Timeout}
+
+	if r.Start != "" {
+		re, err := regexp.Compile(r.Start)
+		if err != nil {
+			return nil, fmt.Errorf("padrão de início de evento inválido %q: %w", r.Start, err)
+		}
+		j.start = append(j.start, re.MatchString)
+	}
+	if r.StartTimestamp {
+		j.start = append(j.start, func(s string) bool {
+			_, ok := timestamp.AtStart(s, time.Local, time.Time{})
+			return ok
+		})
+	}
+	if r.Continue != "" {
+		re, err := regexp.Compile(r.Continue)
+		if err != nil {
+			return nil, fmt.Errorf("padrão de continuação inválido %q: %w", r.Continue, err)
+		}
+		j.cont = re
+	}
+	return j, nil
+}
+
+func (j *Joiner) starts(text string) bool {
+	for _, match := range j.start {
+		if match(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// continues reports whether text belongs to the event before it.
+func (j *Joiner) continues(text string) bool {
+	if j.starts(text) {
+		return false
+	}
+	if j.cont != nil {
+		return j.cont.MatchString(text)
+	}
+	return len(j.start) > 0
+}
+
+// drainTimeout is how long Join keeps offering an event once ctx is done,
+// before deciding that nobody reads its output any more.
+const drainTimeout = time.Second
+
+// Join reads lines from a single source and emits events whose Raw text
+// holds every line of the event separated by "\n". The position and ingest
+// time of an event are those of its first line and its checkpoint that of
+// its last. Lines from different sources must not be mixed on the same
+// input.
+//
+// Join stops when in is closed, emitting the pending event, so a source
+// is stopped by stopping its reader. ctx only abandons events that nobody
+// reads.
+func (j *Joiner) Join(ctx context.Context, in <-chan logline.Line) <-chan logline.Line {
+	out := make(chan logline.Line)
+
+	go func() {
+		defer close(out)
+
+		var event logline.Line
+		var parts []string
+		size := 0
+
+		flush := func() bool {
+			if parts == nil {
+				return true
+			}
+			event.Raw = strings.Join(parts, "\n")
+			parts, size = nil, 0
+			select {
+			case out <- event:
+				return true
+			case <-ctx.Done():
+			}
+			select {
+			case out <- event:
+				return true
+			case <-time.After(drainTimeout):
+				return false
+			}
+		}
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if j.timeout > 0 {
+			timer = time.NewTimer(j.timeout)
+			timer.Stop()
+			defer timer.Stop()
+		}
+
+		for {
+			select {
+			case l, ok := <-in:
+				if !ok {
+					flush()
+					return
+				}
+
+				full := (j.maxLines > 0 && len(parts) >= j.maxLines) ||
+					(j.maxBytes > 0 && size+1+len(l.Raw) > j.maxBytes)
+				if parts == nil || !j.continues(l.Raw) || full {
+					if !flush() {
+						return
+					}
+					event = l
+				}
+				if parts != nil {
+					size++
+				}
+				parts = append(parts, l.Raw)
+				size += len(l.Raw)
+				if event.Checkpoint != l.Checkpoint {
+					// The event is saved at its last line.
+					event.Checkpoint.Release()
+					event.Checkpoint = l.Checkpoint
+				}
+
+				if timer != nil {
+					timer.Reset(j.timeout)
+					timeout = timer.C
+				}
+			case <-timeout:
+				timeout = nil
+				if !flush() {
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,215 @@
+package multiline
+
+import (
+	"context"
+	"logagg/internal/checkpoint"
+	"logagg/internal/logline"
+	"testing"
+	"time"
+)
+
+func join(t *testing.T, r Rule, texts ...string) []logline.Line {
+	t.Helper()
+	j, err := Compile(r)
+	if err != nil {
+		t.Fatalf("Compile() unexpected error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	in := make(chan logline.Line)
+	go func() {
+		for i, text := range texts {
+			n := int64(i + 1)
+			in <- logline.Line{Source: "app.log", Raw: text, Number: n, Checkpoint: &checkpoint.Position{Line: n}}
+		}
+		close(in)
+	}()
+
+	var events []logline.Line
+	for e := range j.Join(ctx, in) {
+		events = append(events, e)
+	}
+	return events
+}
+
+func raws(events []logline.Line) []string {
+	out := make([]string, len(events))
+	for i, e := range events {
+		out[i] = e.Raw
+	}
+	return out
+}
+
+func expectEvents(t *testing.T, got []logline.Line, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d events, got %d: %q", len(want), len(got), raws(got))
+	}
+	for i := range want {
+		if got[i].Raw != want[i] {
+			t.Errorf("event %d: expected %q, got %q", i, want[i], got[i].Raw)
+		}
+	}
+}
+
+func TestJoin_StartPattern(t *testing.T) {
+	events := join(t, Rule{Start: `^\d{4}-\d{2}-\d{2}`},
+		"2024-01-15 10:00:00 ERROR NullPointerException",
+		"    at com.example.Foo.bar(Foo.java:10)",
+		"    at com.example.Main.main(Main.java:3)",
+		"2024-01-15 10:00:01 INFO recovered",
+	)
+
+	expectEvents(t, events,
+		"2024-01-15 10:00:00 ERROR NullPointerException\n    at com.example.Foo.bar(Foo.java:10)\n    at com.example.Main.main(Main.java:3)",
+		"2024-01-15 10:00:01 INFO recovered",
+	)
+	if events[0].Number != 1 || events[1].Number != 4 {
+		t.Errorf("expected events to keep the number of their first line, got %d and %d", events[0].Number, events[1].Number)
+	}
+	if events[0].Checkpoint.Line != 3 {
+		t.Errorf("expected the event to be checkpointed after its last line, got line %d", events[0].Checkpoint.Line)
+	}
+}
+
+func TestJoin_StartTimestamp(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []string
+	}{
+		{
+			name:  "go log package",
+			lines: []string{"2024/01/15 10:00:00 panic: boom", "", "goroutine 1 [running]:", "main.main()", "2024/01/15 10:00:05 restarted"},
+			want:  []string{"2024/01/15 10:00:00 panic: boom\n\ngoroutine 1 [running]:\nmain.main()", "2024/01/15 10:00:05 restarted"},
+		},
+		{
+			name:  "syslog",
+			lines: []string{"Jan 15 10:00:00 web1 app[42]: Traceback (most recent call last):", `  File "app.py", line 3`, "Jan 15 10:00:01 web1 app[42]: recovered"},
+			want:  []string{"Jan 15 10:00:00 web1 app[42]: Traceback (most recent call last):\n  File \"app.py\", line 3", "Jan 15 10:00:01 web1 app[42]: recovered"},
+		},
+		{
+			name:  "bracketed",
+			lines: []string{"[2024-01-15 10:00:00] ERROR failed", "[main] caused by timeout", "[2024-01-15 10:00:01] INFO retrying"},
+			want:  []string{"[2024-01-15 10:00:00] ERROR failed\n[main] caused by timeout", "[2024-01-15 10:00:01] INFO retrying"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectEvents(t, join(t, Rule{StartTimestamp: true}, tt.lines...), tt.want...)
+		})
+	}
+}
+
+func TestJoin_ContinuePattern(t *testing.T) {
+	events := join(t, Rule{Continue: `^(\s+at |Caused by:)`},
+		"java.lang.IllegalStateException: bad",
+		"    at A.a(A.java:1)",
+		"Caused by: java.io.IOException",
+		"    at B.b(B.java:2)",
+		"next event",
+	)
+
+	expectEvents(t, events,
+		"java.lang.IllegalStateException: bad\n    at A.a(A.java:1)\nCaused by: java.io.IOException\n    at B.b(B.java:2)",
+		"next event",
+	)
+}
+
+func TestJoin_StartWinsOverContinue(t *testing.T) {
+	events := join(t, Rule{Start: `^\[`, Continue: `^\s`},
+		"[1] first",
+		"  indented",
+		"not indented",
+		"[2] second",
+	)
+
+	expectEvents(t, events, "[1] first\n  indented", "not indented", "[2] second")
+}
+
+func TestJoin_OrphanContinuation(t *testing.T) {
+	events := join(t, Rule{Start: `^START`}, "  dangling", "START x", "  y")
+
+	expectEvents(t, events, "  dangling", "START x\n  y")
+}
+
+func TestJoin_MaxLines(t *testing.T) {
+	events := join(t, Rule{Start: `^E`, MaxLines: 2}, "E1", "a", "b", "c", "E2")
+
+	expectEvents(t, events, "E1\na", "b\nc", "E2")
+}
+
+func TestJoin_MaxBytes(t *testing.T) {
+	events := join(t, Rule{Start: `^E`, MaxBytes: 8}, "E1", "abc", "def", "E2")
+
+	// "E1\nabc" is 6 bytes; adding "\ndef" would make 10.
+	expectEvents(t, events, "E1\nabc", "def", "E2")
+}
+
+func TestJoin_DisabledRulePassesThrough(t *testing.T) {
+	events := join(t, Rule{}, "a", "  b", "c")
+
+	expectEvents(t, events, "a", "  b", "c")
+}
+
+func TestJoin_TimeoutFlushesPendingEvent(t *testing.T) {
+	j, err := Compile(Rule{Start: `^E`, Timeout: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Compile() unexpected error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	in := make(chan logline.Line)
+	out := j.Join(ctx, in)
+
+	in <- logline.Line{Raw: "E1"}
+	in <- logline.Line{Raw: "  detail"}
+
+	select {
+	case e := <-out:
+		if e.Raw != "E1\n  detail" {
+			t.Errorf("unexpected event %q", e.Raw)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pending event was not flushed after the timeout")
+	}
+}
+
+func TestJoin_StoppedSourceKeepsPendingEvent(t *testing.T) {
+	j, err := Compile(Rule{Start: `^E`})
+	if err != nil {
+		t.Fatalf("Compile() unexpected error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan logline.Line)
+	out := j.Join(ctx, in)
+
+	in <- logline.Line{Raw: "E1"}
+	in <- logline.Line{Raw: "  detail"}
+	// A reader stopped by Ctrl+C or a reload closes its output.
+	cancel()
+	close(in)
+
+	var got []string
+	for e := range out {
+		got = append(got, e.Raw)
+	}
+	if len(got) != 1 || got[0] != "E1\n  detail" {
+		t.Errorf("expected the pending event to be emitted, got %q", got)
+	}
+}
+
+func TestCompile_InvalidPatterns(t *testing.T) {
+	if _, err := Compile(Rule{Start: "("}); err == nil {
+		t.Error("Compile() error = nil, want error for invalid start pattern")
+	}
+	if _, err := Compile(Rule{Continue: "["}); err == nil {
+		t.Error("Compile() error = nil, want error for invalid continuation pattern")
+	}
+}
@@ -0,0 +1,122 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"logagg/internal/level"
+	"logagg/internal/logline"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ColorMode says when output is colorized.
+type ColorMode string
+
+const (
+	ColorAuto   ColorMode = "auto"
+	ColorAlways ColorMode = "always"
+	ColorNever  ColorMode = "never"
+)
+
+func ParseColorMode(s string) (ColorMode, error) {
+	switch m := ColorMode(s); m {
+	case ColorAuto, ColorAlways, ColorNever:
+		return m, nil
+	}
+	return "", fmt.Errorf("modo de cor inválido %q: use always, never ou auto", s)
+}
+
+// Enabled reports whether output written to f should be colorized. In
+// auto mode that is when f is a terminal, NO_COLOR is not set and TERM is
+// not "dumb".
+func (m ColorMode) Enabled(f *os.File) bool {
+	switch m {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+const (
+	reset     = "\x1b[0m"
+	highlight = "\x1b[1;7m"
+)
+
+// sourceColors are given to sources in the order they first appear, so
+// up to eight sources never share one; further sources take them again
+// from the start. Red and yellow are left to the levels.
+var sourceColors = []string{
+	"\x1b[36m", "\x1b[35m", "\x1b[34m", "\x1b[32m",
+	"\x1b[96m", "\x1b[95m", "\x1b[94m", "\x1b[92m",
+}
+
+var levelColors = map[level.Level]string{
+	level.Trace: "\x1b[2m",
+	level.Debug: "\x1b[2m",
+	level.Warn:  "\x1b[33m",
+	level.Error: "\x1b[31m",
+	level.Fatal: "\x1b[1;31m",
+}
+
+// paint wraps s in style, leaving it plain when either is empty.
+func paint(s, style string) string {
+	if s == "" || style == "" {
+		return s
+	}
+	return style + s + reset
+}
+
+// assigned remembers the color given to each source name.
+var assigned = struct {
+	sync.Mutex
+	colors map[string]string
+}{colors: make(map[string]string)}
+
+func sourceColor(name string) string {
+	assigned.Lock()
+	defer assigned.Unlock()
+	c, ok := assigned.colors[name]
+	if !ok {
+		c = sourceColors[len(assigned.colors)%len(sourceColors)]
+		assigned.colors[name] = c
+	}
+	return c
+}
+
+// Color prints lines like Text with ANSI colors: the source prefix in a
+// color of its own, warnings and errors in yellow and red, and the ranges
+// returned by Highlight, if set, in reverse video.
+type Color struct {
+	Highlight func(string) [][2]int
+}
+
+func (c Color) Format(w io.Writer, l logline.Line) error {
+	var b strings.Builder
+	name := l.Name()
+	b.WriteString(paint("["+name+"]", sourceColor(name)) + " - ")
+
+	lv, _ := level.Parse(l.Level)
+	base := levelColors[lv]
+	var spans [][2]int
+	if c.Highlight != nil {
+		spans = c.Highlight(l.Raw)
+	}
+	prev := 0
+	for _, s := range spans {
+		b.WriteString(paint(l.Raw[prev:s[0]], base))
+		b.WriteString(paint(l.Raw[s[0]:s[1]], highlight))
+		prev = s[1]
+	}
+	b.WriteString(paint(l.Raw[prev:], base))
+	b.WriteByte('\n')
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,106 @@
+package output
+
+import (
+	"bytes"
+	"logagg/internal/logline"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseColorMode(t *testing.T) {
+	for _, s := range []string{"auto", "always", "never"} {
+		if m, err := ParseColorMode(s); err != nil || string(m) != s {
+			t.Errorf("ParseColorMode(%q) = %q, %v", s, m, err)
+		}
+	}
+	if _, err := ParseColorMode("sometimes"); err == nil {
+		t.Error("ParseColorMode(\"sometimes\") expected an error")
+	}
+}
+
+func TestColorMode_Enabled(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if !ColorAlways.Enabled(f) {
+		t.Error("always: Enabled() = false, want true")
+	}
+	if ColorNever.Enabled(f) {
+		t.Error("never: Enabled() = true, want false")
+	}
+	if ColorAuto.Enabled(f) {
+		t.Error("auto on a regular file: Enabled() = true, want false")
+	}
+}
+
+func TestColor_Format(t *testing.T) {
+	c := Color{Highlight: func(s string) [][2]int {
+		if i := strings.Index(s, "timeout"); i >= 0 {
+			return [][2]int{{i, i + len("timeout")}}
+		}
+		return nil
+	}}
+
+	tests := []struct {
+		name string
+		line logline.Line
+		want string
+	}{
+		{
+			name: "plain info line",
+			line: logline.Line{Source: "/var/log/app.log", Raw: "INFO started", Level: "info"},
+			want: sourceColor("app.log") + "[app.log]" + reset + " - INFO started\n",
+		},
+		{
+			name: "error with highlighted match",
+			line: logline.Line{Source: "app.log", Raw: "ERROR timeout talking to db", Level: "error"},
+			want: sourceColor("app.log") + "[app.log]" + reset + " - " +
+				"\x1b[31mERROR " + reset + highlight + "timeout" + reset + "\x1b[31m talking to db" + reset + "\n",
+		},
+		{
+			name: "warning",
+			line: logline.Line{Source: "app.log", Raw: "WARN slow", Level: "warn"},
+			want: sourceColor("app.log") + "[app.log]" + reset + " - \x1b[33mWARN slow" + reset + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := c.Format(&buf, tt.line); err != nil {
+				t.Fatalf("Format() unexpected error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Format() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSourceColor_Distinct(t *testing.T) {
+	assigned.Lock()
+	assigned.colors = make(map[string]string)
+	assigned.Unlock()
+
+	if sourceColor("app.log") != sourceColor("app.log") {
+		t.Error("sourceColor() differs between calls for the same name")
+	}
+	// api.log, auth.log and worker.log shared a color when it was derived
+	// from a hash of the name.
+	seen := map[string]string{}
+	for _, name := range []string{"app.log", "db.log", "web.log", "api.log", "auth.log", "cache.log", "queue.log", "worker.log"} {
+		c := sourceColor(name)
+		if other, ok := seen[c]; ok {
+			t.Errorf("sourceColor() gave %s the color of %s", name, other)
+		}
+		seen[c] = name
+	}
+	if got := sourceColor("nginx.log"); got != sourceColor("app.log") {
+		t.Errorf("expected the ninth source to reuse the first color, got %q", got)
+	}
+}
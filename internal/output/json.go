@@ -0,0 +1,82 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"logagg/internal/logline"
+	"time"
+)
+
+// jsonRecord is the shape of a line in the JSON based formats.
+type jsonRecord struct {
+	Source    string         `json:"source"`
+	Path      string         `json:"path"`
+	Line      int64          `json:"line"`
+	Offset    int64          `json:"offset"`
+	Timestamp *time.Time     `json:"timestamp,omitempty"`
+	Ingested  time.Time      `json:"ingested"`
+	Level     string         `json:"level,omitempty"`
+	Message   string         `json:"message"`
+	Fields    map[string]any `json:"fields,omitempty"`
+}
+
+func newJSONRecord(l logline.Line) jsonRecord {
+	r := jsonRecord{
+		Source:   l.Name(),
+		Path:     l.Source,
+		Line:     l.Number,
+		Offset:   l.Offset,
+		Ingested: l.Ingested,
+		Fields:   l.Fields,
+	}
+	if !l.Time.IsZero() {
+		t := l.Time
+		r.Timestamp = &t
+	}
+	r.Level, _ = l.Field("level")
+	r.Message, _ = l.Field("msg")
+	return r
+}
+
+// NDJSON prints one JSON object per line, ready to be piped into jq or a
+// line oriented ingestion script.
+type NDJSON struct{}
+
+func (NDJSON) Format(w io.Writer, l logline.Line) error {
+	return json.NewEncoder(w).Encode(newJSONRecord(l))
+}
+
+// JSON prints a single JSON array holding one object per line. Elements
+// are written as they arrive, so the array is only complete once the input
+// ends.
+type JSON struct {
+	n int
+}
+
+func (j *JSON) Begin(w io.Writer) error {
+	_, err := io.WriteString(w, "[\n")
+	return err
+}
+
+func (j *JSON) Format(w io.Writer, l logline.Line) error {
+	b, err := json.Marshal(newJSONRecord(l))
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if j.n == 0 {
+		sep = ""
+	}
+	j.n++
+	_, err = io.WriteString(w, sep+string(b))
+	return err
+}
+
+func (j *JSON) End(w io.Writer) error {
+	end := "\n]\n"
+	if j.n == 0 {
+		end = "]\n"
+	}
+	_, err := io.WriteString(w, end)
+	return err
+}
@@ -0,0 +1,129 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"logagg/internal/logline"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleLines() []logline.Line {
+	return []logline.Line{
+		{
+			Source: "/var/log/app.log",
+			Raw:    "ERROR payment declined",
+			Offset: 0,
+			Number: 1,
+			Time:   time.Date(2024, 1, 15, 10, 23, 45, 0, time.UTC),
+			Fields: map[string]any{"level": "error", "status": 402},
+		},
+		{
+			Source: "/var/log/db.log",
+			Raw:    "slow query",
+			Offset: 42,
+			Number: 3,
+		},
+	}
+}
+
+func writeAll(t *testing.T, f Formatter, lines []logline.Line) string {
+	t.Helper()
+	ch := make(chan logline.Line, len(lines))
+	for _, l := range lines {
+		ch <- l
+	}
+	close(ch)
+
+	var buf bytes.Buffer
+	if err := Write(&buf, ch, f); err != nil {
+		t.Fatalf("Write() unexpected error = %v", err)
+	}
+	return buf.String()
+}
+
+func TestNDJSON_OneObjectPerLine(t *testing.T) {
+	out := writeAll(t, NDJSON{}, sampleLines())
+
+	rows := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %q", len(rows), out)
+	}
+
+	var first map[string]any
+	if err := json.Unmarshal([]byte(rows[0]), &first); err != nil {
+		t.Fatalf("line is not valid JSON: %v", err)
+	}
+	expected := map[string]any{
+		"source":    "app.log",
+		"path":      "/var/log/app.log",
+		"line":      float64(1),
+		"offset":    float64(0),
+		"timestamp": "2024-01-15T10:23:45Z",
+		"level":     "error",
+		"message":   "ERROR payment declined",
+	}
+	for k, v := range expected {
+		if first[k] != v {
+			t.Errorf("field %q: expected %v, got %v", k, v, first[k])
+		}
+	}
+	if fields, ok := first["fields"].(map[string]any); !ok || fields["status"] != float64(402) {
+		t.Errorf("expected parsed fields to be kept, got %v", first["fields"])
+	}
+
+	var second map[string]any
+	if err := json.Unmarshal([]byte(rows[1]), &second); err != nil {
+		t.Fatalf("line is not valid JSON: %v", err)
+	}
+	if _, ok := second["timestamp"]; ok {
+		t.Error("expected no timestamp when the line has none")
+	}
+}
+
+func TestNDJSON_NormalisedLevel(t *testing.T) {
+	lines := []logline.Line{
+		{Source: "app.log", Raw: `{"level":"WARNING"}`, Level: "warn", Fields: map[string]any{"level": "WARNING"}},
+		{Source: "app.log", Raw: `{"level":50}`, Level: "error", Fields: map[string]any{"level": float64(50)}},
+	}
+	out := writeAll(t, NDJSON{}, lines)
+
+	rows := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	for i, want := range []string{"warn", "error"} {
+		var row map[string]any
+		if err := json.Unmarshal([]byte(rows[i]), &row); err != nil {
+			t.Fatalf("line is not valid JSON: %v", err)
+		}
+		if row["level"] != want {
+			t.Errorf("line %d: expected level %q, got %v", i+1, want, row["level"])
+		}
+	}
+}
+
+func TestJSON_ValidArray(t *testing.T) {
+	out := writeAll(t, &JSON{}, sampleLines())
+
+	var rows []map[string]any
+	if err := json.Unmarshal([]byte(out), &rows); err != nil {
+		t.Fatalf("output is not a valid JSON array: %v\n%s", err, out)
+	}
+	if len(rows) != 2 {
+		t.Errorf("expected 2 elements, got %d", len(rows))
+	}
+	if strings.Count(out, "\n") != 4 {
+		t.Errorf("expected one element per line, got %q", out)
+	}
+}
+
+func TestJSON_EmptyInput(t *testing.T) {
+	out := writeAll(t, &JSON{}, nil)
+
+	var rows []map[string]any
+	if err := json.Unmarshal([]byte(out), &rows); err != nil {
+		t.Fatalf("output is not a valid JSON array: %v\n%s", err, out)
+	}
+	if len(rows) != 0 {
+		t.Errorf("expected empty array, got %d elements", len(rows))
+	}
+}
@@ -0,0 +1,83 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"logagg/internal/logline"
+	"sort"
+	"strings"
+)
+
+// Formatter renders a line for display.
+type Formatter interface {
+	Format(w io.Writer, l logline.Line) error
+}
+
+// Framer is implemented by formatters that wrap the whole stream, such as
+// a JSON array that has to be opened and closed.
+type Framer interface {
+	Begin(w io.Writer) error
+	End(w io.Writer) error
+}
+
+var formats = map[string]func() Formatter{
+	"text":   func() Formatter { return Text{} },
+	"json":   func() Formatter { return &JSON{} },
+	"ndjson": func() Formatter { return NDJSON{} },
+}
+
+// Register makes a format available to New under the given name.
+func Register(name string, factory func() Formatter) {
+	formats[name] = factory
+}
+
+// New returns a fresh formatter for the named format.
+func New(name string) (Formatter, error) {
+	factory, ok := formats[name]
+	if !ok {
+		return nil, fmt.Errorf("formato de saída desconhecido %q: use %s", name, strings.Join(Names(), ", "))
+	}
+	return factory(), nil
+}
+
+// Names lists the registered formats in alphabetical order.
+func Names() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Text prints lines as "[file] - text".
+type Text struct{}
+
+func (Text) Format(w io.Writer, l logline.Line) error {
+	_, err := fmt.Fprintf(w, "[%s] - %s\n", l.Name(), l.Raw)
+	return err
+}
+
+// Write formats every line received from lines until the channel closes or
+// writing fails. The checkpoint of each line is committed once the line is
+// written.
+func Write(w io.Writer, lines <-chan logline.Line, f Formatter) error {
+	framer, framed := f.(Framer)
+	if framed {
+		if err := framer.Begin(w); err != nil {
+			return err
+		}
+	}
+	for l := range lines {
+		if err := f.Format(w, l); err != nil {
+			return err
+		}
+		if l.Checkpoint != nil {
+			l.Checkpoint.Commit()
+		}
+	}
+	if framed {
+		return framer.End(w)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"logagg/internal/checkpoint"
+	"logagg/internal/logline"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestText_Format(t *testing.T) {
+	var buf bytes.Buffer
+	l := logline.Line{Source: "/var/log/app.log", Raw: "2024-01-15 10:23:45 INFO started"}
+
+	if err := (Text{}).Format(&buf, l); err != nil {
+		t.Fatalf("Format() unexpected error = %v", err)
+	}
+
+	want := "[app.log] - 2024-01-15 10:23:45 INFO started\n"
+	if buf.String() != want {
+		t.Errorf("Format() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	lines := make(chan logline.Line, 2)
+	lines <- logline.Line{Source: "app.log", Raw: "one"}
+	lines <- logline.Line{Source: "db.log", Raw: "two"}
+	close(lines)
+
+	var buf bytes.Buffer
+	if err := Write(&buf, lines, Text{}); err != nil {
+		t.Fatalf("Write() unexpected error = %v", err)
+	}
+
+	want := "[app.log] - one\n[db.log] - two\n"
+	if buf.String() != want {
+		t.Errorf("Write() = %q, want %q", buf.String(), want)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("broken pipe") }
+
+func TestWrite_StopsOnError(t *testing.T) {
+	lines := make(chan logline.Line, 1)
+	lines <- logline.Line{Source: "app.log", Raw: "one"}
+	close(lines)
+
+	if err := Write(failingWriter{}, lines, Text{}); err == nil {
+		t.Error("Write() error = nil, want error from writer")
+	}
+}
+
+func TestWrite_CommitsWrittenLines(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(path, []byte("one\n"), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	store := checkpoint.NewMemory()
+	id, _, _ := store.Lookup(f)
+	send := func() <-chan logline.Line {
+		lines := make(chan logline.Line, 1)
+		lines <- logline.Line{Source: path, Raw: "one", Checkpoint: store.Cursor().Next(id, path, 4, 1)}
+		close(lines)
+		return lines
+	}
+
+	if err := Write(failingWriter{}, send(), Text{}); err == nil {
+		t.Fatal("Write() error = nil, want error from writer")
+	}
+	if _, _, ok := store.Lookup(f); ok {
+		t.Fatal("checkpoint committed for a line that failed to be written")
+	}
+
+	if err := Write(io.Discard, send(), Text{}); err != nil {
+		t.Fatalf("Write() unexpected error = %v", err)
+	}
+	if _, e, ok := store.Lookup(f); !ok || e.Offset != 4 {
+		t.Errorf("expected checkpoint at offset 4 after writing, got %+v (found %v)", e, ok)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, name := range []string{"text", "json", "ndjson"} {
+		if _, err := New(name); err != nil {
+			t.Errorf("New(%q) unexpected error = %v", name, err)
+		}
+	}
+	if _, err := New("xml"); err == nil {
+		t.Error("New() with unknown format error = nil, want error")
+	}
+}
+
+func TestNew_ReturnsFreshFormatter(t *testing.T) {
+	a, _ := New("json")
+	b, _ := New("json")
+	if a == b {
+		t.Error("expected New to return a new formatter on each call")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("raw", func() Formatter { return rawFormatter{} })
+	defer delete(formats, "raw")
+
+	f, err := New("raw")
+	if err != nil {
+		t.Fatalf("New() unexpected error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	f.Format(&buf, logline.Line{Source: "app.log", Raw: "hello"})
+	if buf.String() != "hello\n" {
+		t.Errorf("Format() = %q, want %q", buf.String(), "hello\n")
+	}
+}
+
+type rawFormatter struct{}
+
+func (rawFormatter) Format(w io.Writer, l logline.Line) error {
+	_, err := io.WriteString(w, l.Raw+"\n")
+	return err
+}
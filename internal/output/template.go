@@ -0,0 +1,188 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"logagg/internal/level"
+	"logagg/internal/logline"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// templateRecord is what a --template sees as dot.
+type templateRecord struct {
+	// Source is the file name and Path the full path of the source.
+	Source string
+	Path   string
+	Line   int64
+	Offset int64
+	// Time is zero when the line has no timestamp.
+	Time     time.Time
+	Ingested time.Time
+	Level    string
+	// Message is the parsed message, or the raw text.
+	Message string
+	Raw     string
+	Fields  map[string]any
+
+	line logline.Line
+}
+
+// Field returns the named field as Line.Field does, or "" when missing.
+func (r templateRecord) Field(name string) string {
+	v, _ := r.line.Field(name)
+	return v
+}
+
+// Template prints each line through a text/template. Templates that do not
+// end in a newline get one added.
+type Template struct {
+	tmpl *template.Template
+	now  func() time.Time
+}
+
+// NewTemplate parses and checks text. With colors false the color helpers return their
+// text unchanged, so the same template works on a terminal and in a pipe.
+func NewTemplate(text string, colors bool) (*Template, error) {
+	t := &Template{now: time.Now}
+	tmpl, err := template.New("template").Funcs(t.funcs(colors)).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("template inválido: %w", err)
+	}
+	t.tmpl = tmpl
+	// A dry run on an empty line catches misspelled fields and unknown
+	// colors before any file is read.
+	if err := t.Format(io.Discard, logline.Line{}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *Template) funcs(colors bool) template.FuncMap {
+	style := func(code, s string) string {
+		if !colors {
+			return s
+		}
+		return paint(s, code)
+	}
+	return template.FuncMap{
+		"pad":   pad,
+		"lpad":  lpad,
+		"trunc": trunc,
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"json":  toJSON,
+		"ago":   func(at time.Time) string { return ago(t.now(), at) },
+		"color": func(name, s string) (string, error) {
+			code, ok := namedColors[name]
+			if !ok {
+				return "", fmt.Errorf("cor desconhecida %q", name)
+			}
+			return style(code, s), nil
+		},
+		"levelColor": func(lv, s string) string {
+			l, _ := level.Parse(lv)
+			return style(levelColors[l], s)
+		},
+		"sourceColor": func(name, s string) string {
+			return style(sourceColor(name), s)
+		},
+	}
+}
+
+func (t *Template) Format(w io.Writer, l logline.Line) error {
+	r := templateRecord{
+		Source:   l.Name(),
+		Path:     l.Source,
+		Line:     l.Number,
+		Offset:   l.Offset,
+		Time:     l.Time,
+		Ingested: l.Ingested,
+		Level:    l.Level,
+		Raw:      l.Raw,
+		Fields:   l.Fields,
+		line:     l,
+	}
+	r.Message, _ = l.Field("msg")
+
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, r); err != nil {
+		return fmt.Errorf("template inválido: %w", err)
+	}
+	if b := buf.Bytes(); len(b) == 0 || b[len(b)-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+var namedColors = map[string]string{
+	"red":     "\x1b[31m",
+	"green":   "\x1b[32m",
+	"yellow":  "\x1b[33m",
+	"blue":    "\x1b[34m",
+	"magenta": "\x1b[35m",
+	"cyan":    "\x1b[36m",
+	"gray":    "\x1b[90m",
+	"bold":    "\x1b[1m",
+	"dim":     "\x1b[2m",
+}
+
+// pad fills the text of v with spaces on the right up to width runes.
+func pad(width int, v any) string {
+	s := fmt.Sprint(v)
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// lpad fills the text of v with spaces on the left up to width runes.
+func lpad(width int, v any) string {
+	s := fmt.Sprint(v)
+	if n := utf8.RuneCountInString(s); n < width {
+		return strings.Repeat(" ", width-n) + s
+	}
+	return s
+}
+
+// trunc cuts the text of v to at most width runes, ending it with "…"
+// when cut.
+func trunc(width int, v any) string {
+	s := fmt.Sprint(v)
+	if width <= 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+func toJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// ago tells how long before now t was, in its largest whole unit, such as
+// "45s", "12m", "3h" or "2d". A zero t gives "".
+func ago(now, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	d := now.Sub(t)
+	if d < 0 {
+		d = 0
+	}
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d/time.Second))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	}
+	return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+}
@@ -0,0 +1,119 @@
+package output
+
+import (
+	"bytes"
+	"logagg/internal/logline"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplate_Format(t *testing.T) {
+	at := time.Date(2024, 1, 15, 10, 23, 45, 0, time.UTC)
+	l := logline.Line{
+		Source:  "/var/log/api.log",
+		Raw:     `{"level":"error","msg":"upstream failed","http":{"status":502}}`,
+		Number:  7,
+		Time:    at,
+		Level:   "error",
+		Message: "upstream failed",
+		Fields:  map[string]any{"http": map[string]any{"status": 502}, "user": "ana"},
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "example from the docs",
+			text: `{{.Time.Format "15:04:05"}} {{.Source | printf "%-12s"}} {{.Level}} {{.Message}}`,
+			want: "10:23:45 api.log      error upstream failed\n",
+		},
+		{
+			name: "fields and helpers",
+			text: "{{.Field \"http.status\"}} {{.Level | upper | pad 6}}|{{lpad 4 .Line}}|{{trunc 8 .Message}}\n",
+			want: "502 ERROR |   7|upstrea…\n",
+		},
+		{
+			name: "json",
+			text: `{{json .Fields}} {{.Field "user" | json}} {{.Field "missing" | json}}`,
+			want: `{"http":{"status":502},"user":"ana"} "ana" ""` + "\n",
+		},
+		{
+			name: "relative time",
+			text: `{{ago .Time}} ago, {{ago .Ingested}}.`,
+			want: "2h ago, .\n",
+		},
+		{
+			name: "colors disabled",
+			text: `{{color "red" "x"}} {{levelColor .Level .Level}} {{sourceColor .Source .Source}}`,
+			want: "x error api.log\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := NewTemplate(tt.text, false)
+			if err != nil {
+				t.Fatalf("NewTemplate() unexpected error = %v", err)
+			}
+			tmpl.now = func() time.Time { return at.Add(2*time.Hour + 5*time.Minute) }
+
+			var buf bytes.Buffer
+			if err := tmpl.Format(&buf, l); err != nil {
+				t.Fatalf("Format() unexpected error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Format() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplate_Colors(t *testing.T) {
+	tmpl, err := NewTemplate(`{{color "red" "x"}} {{levelColor .Level "!"}}`, true)
+	if err != nil {
+		t.Fatalf("NewTemplate() unexpected error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Format(&buf, logline.Line{Level: "warn"}); err != nil {
+		t.Fatalf("Format() unexpected error = %v", err)
+	}
+	want := "\x1b[31mx" + reset + " \x1b[33m!" + reset + "\n"
+	if buf.String() != want {
+		t.Errorf("Format() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestTemplate_Errors(t *testing.T) {
+	if _, err := NewTemplate("{{.Source", false); err == nil || !strings.Contains(err.Error(), "template inválido") {
+		t.Errorf("NewTemplate() error = %v, want a parse error", err)
+	}
+
+	for _, text := range []string{`{{color "purple" .Source}}`, "{{.Sourc}}"} {
+		if _, err := NewTemplate(text, false); err == nil {
+			t.Errorf("NewTemplate(%q) expected an error", text)
+		}
+	}
+}
+
+func TestAgo(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{-time.Second, "0s"},
+		{45 * time.Second, "45s"},
+		{12*time.Minute + 30*time.Second, "12m"},
+		{3 * time.Hour, "3h"},
+		{50 * time.Hour, "2d"},
+	}
+	for _, tt := range tests {
+		if got := ago(now, now.Add(-tt.d)); got != tt.want {
+			t.Errorf("ago(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
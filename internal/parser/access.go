@@ -0,0 +1,150 @@
+package parser
+
+import (
+	"fmt"
+	"logagg/internal/logline"
+	"logagg/internal/timestamp"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Log formats of access logs, in nginx log_format syntax. Apache's common
+// and combined formats produce the same lines; $remote_ident stands for
+// Apache's %l, which nginx always writes as "-".
+const (
+	CommonLogFormat   = `$remote_addr $remote_ident $remote_user [$time_local] "$request" $status $body_bytes_sent`
+	CombinedLogFormat = CommonLogFormat + ` "$http_referer" "$http_user_agent"`
+)
+
+// timeLocalLayout is the layout of $time_local and Apache's %t.
+const timeLocalLayout = "02/Jan/2006:15:04:05 -0700"
+
+// Access parses access log lines described by an nginx log_format string,
+// recording each $variable as a field of the same name. $request is also
+// split into method, path and protocol. Fields logged as "-" are left out.
+//
+// The level is derived from the status: error for 5xx, warn for 4xx and
+// info otherwise.
+type Access struct {
+	re *regexp.Regexp
+}
+
+var accessVar = regexp.MustCompile(`\$(\{[A-Za-z0-9_]+\}|[A-Za-z0-9_]+)`)
+
+// NewAccess compiles an nginx log_format string. A variable matches up to
+// the first character of the literal text that follows it, or up to a
+// space at the end of the format, so formats must separate variables with
+// at least one character.
+func NewAccess(format string) (*Access, error) {
+	locs := accessVar.FindAllStringSubmatchIndex(format, -1)
+	if len(locs) == 0 {
+		return nil, fmt.Errorf("log_format sem variáveis: %q", format)
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	seen := make(map[string]bool)
+	prev := 0
+	for i, loc := range locs {
+		b.WriteString(regexp.QuoteMeta(format[prev:loc[0]]))
+		name := strings.Trim(format[loc[2]:loc[3]], "{}")
+		if seen[name] {
+			return nil, fmt.Errorf("log_format com a variável $%s repetida", name)
+		}
+		seen[name] = true
+
+		next := len(format)
+		if i+1 < len(locs) {
+			next = locs[i+1][0]
+		}
+		if loc[1] == next && next < len(format) {
+			return nil, fmt.Errorf("log_format com variáveis sem separação em $%s", name)
+		}
+		stop := " "
+		if loc[1] < len(format) {
+			stop = format[loc[1] : loc[1]+1]
+		}
+		fmt.Fprintf(&b, "(?P<%s>[^%s]*)", name, regexp.QuoteMeta(stop))
+		prev = loc[1]
+	}
+	b.WriteString(regexp.QuoteMeta(format[prev:]))
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("log_format inválido %q: %w", format, err)
+	}
+	return &Access{re: re}, nil
+}
+
+func (a *Access) Parse(l *logline.Line) bool {
+	m := a.re.FindStringSubmatch(l.Raw)
+	if m == nil {
+		return false
+	}
+
+	fields := make(map[string]any, len(m)+2)
+	for i, name := range a.re.SubexpNames() {
+		if i == 0 || m[i] == "-" {
+			continue
+		}
+		fields[name] = m[i]
+	}
+	if req, ok := fields["request"].(string); ok {
+		parts := strings.Fields(req)
+		if len(parts) == 3 {
+			fields["method"], fields["path"], fields["protocol"] = parts[0], parts[1], parts[2]
+		}
+		l.Message = req
+	}
+
+	l.Fields = fields
+	if t, ok := accessTime(fields); ok {
+		l.Time = t
+	}
+	if status, err := strconv.Atoi(fmt.Sprint(fields["status"])); err == nil {
+		switch {
+		case status >= 500:
+			l.Level = "error"
+		case status >= 400:
+			l.Level = "warn"
+		default:
+			l.Level = "info"
+		}
+	}
+	return true
+}
+
+func accessTime(fields map[string]any) (time.Time, bool) {
+	if v, ok := fields["time_local"].(string); ok {
+		if t, err := time.Parse(timeLocalLayout, v); err == nil {
+			return t, true
+		}
+	}
+	if v, ok := fields["time_iso8601"].(string); ok {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t, true
+		}
+	}
+	if v, ok := fields["msec"].(string); ok {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return timestamp.Epoch(f), true
+		}
+	}
+	return time.Time{}, false
+}
+
+var (
+	commonLog   = mustAccess(CommonLogFormat)
+	combinedLog = mustAccess(CombinedLogFormat)
+)
+
+func mustAccess(format string) *Access {
+	a, err := NewAccess(format)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
@@ -0,0 +1,118 @@
+package parser
+
+import (
+	"logagg/internal/logline"
+	"testing"
+	"time"
+)
+
+func TestAccess_Combined(t *testing.T) {
+	l := logline.Line{Raw: `203.0.113.9 - frank [10/Oct/2023:13:55:36 -0700] "GET /api/pay?id=1 HTTP/1.1" 503 2326 "https://example.com/" "Mozilla/5.0 (X11; Linux x86_64)"`}
+	if !combinedLog.Parse(&l) {
+		t.Fatal("Parse() = false, want true")
+	}
+
+	want := map[string]string{
+		"remote_addr":     "203.0.113.9",
+		"remote_user":     "frank",
+		"method":          "GET",
+		"path":            "/api/pay?id=1",
+		"protocol":        "HTTP/1.1",
+		"status":          "503",
+		"body_bytes_sent": "2326",
+		"http_referer":    "https://example.com/",
+		"http_user_agent": "Mozilla/5.0 (X11; Linux x86_64)",
+	}
+	for k, v := range want {
+		if got, _ := l.Field(k); got != v {
+			t.Errorf("Field(%s) = %q, want %q", k, got, v)
+		}
+	}
+	if _, ok := l.Fields["remote_ident"]; ok {
+		t.Error("expected \"-\" fields to be left out")
+	}
+
+	wantTime := time.Date(2023, 10, 10, 20, 55, 36, 0, time.UTC)
+	if !l.Time.Equal(wantTime) {
+		t.Errorf("Time = %v, want %v", l.Time, wantTime)
+	}
+	if l.Level != "error" {
+		t.Errorf("Level = %q, want %q", l.Level, "error")
+	}
+	if l.Message != "GET /api/pay?id=1 HTTP/1.1" {
+		t.Errorf("Message = %q, want the request line", l.Message)
+	}
+}
+
+func TestAccess_CommonApache(t *testing.T) {
+	l := logline.Line{Raw: `127.0.0.1 user-identifier frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 404 -`}
+	if !commonLog.Parse(&l) {
+		t.Fatal("Parse() = false, want true")
+	}
+	if got, _ := l.Field("remote_ident"); got != "user-identifier" {
+		t.Errorf("Field(remote_ident) = %q, want %q", got, "user-identifier")
+	}
+	if _, ok := l.Fields["body_bytes_sent"]; ok {
+		t.Error("expected bytes logged as \"-\" to be left out")
+	}
+	if l.Level != "warn" {
+		t.Errorf("Level = %q, want %q", l.Level, "warn")
+	}
+
+	combined := logline.Line{Raw: l.Raw + ` "-" "curl/8.0"`}
+	if commonLog.Parse(&combined) {
+		t.Error("expected the common format not to accept a combined line")
+	}
+}
+
+func TestAccess_CustomFormat(t *testing.T) {
+	p, err := New(`nginx:$remote_addr [$time_iso8601] "$request" $status rt=$request_time up=${upstream_addr}`)
+	if err != nil {
+		t.Fatalf("New() unexpected error = %v", err)
+	}
+
+	l := logline.Line{Raw: `10.0.0.1 [2024-03-01T10:00:00+00:00] "POST /login HTTP/2.0" 200 rt=0.012 up=10.1.0.5:8080`}
+	if !p.Parse(&l) {
+		t.Fatal("Parse() = false, want true")
+	}
+	if got, _ := l.Field("request_time"); got != "0.012" {
+		t.Errorf("Field(request_time) = %q, want %q", got, "0.012")
+	}
+	if got, _ := l.Field("upstream_addr"); got != "10.1.0.5:8080" {
+		t.Errorf("Field(upstream_addr) = %q, want %q", got, "10.1.0.5:8080")
+	}
+	if !l.Time.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Time = %v, want 2024-03-01 10:00:00 UTC", l.Time)
+	}
+
+	other := logline.Line{Raw: "not an access line"}
+	if p.Parse(&other) {
+		t.Error("expected a non matching line to be rejected")
+	}
+}
+
+func TestNewAccess_Errors(t *testing.T) {
+	for _, format := range []string{
+		"no variables here",
+		"$status$body_bytes_sent",
+		"$status $status",
+	} {
+		if _, err := NewAccess(format); err == nil {
+			t.Errorf("NewAccess(%q) error = nil, want error", format)
+		}
+		// A nil *Access would make a non-nil Parser.
+		if p, err := New(NginxPrefix + format); err == nil || p != nil {
+			t.Errorf("New(%q) = %v, %v, want nil and an error", NginxPrefix+format, p, err)
+		}
+	}
+}
+
+func TestAuto_DetectsAccessLogs(t *testing.T) {
+	l := logline.Line{Raw: `10.0.0.1 - - [10/Oct/2023:13:55:36 +0000] "GET / HTTP/1.1" 200 612 "-" "curl/8.0"`}
+	if !(&Auto{}).Parse(&l) {
+		t.Fatal("expected combined access line to be detected")
+	}
+	if got, _ := l.Field("http_user_agent"); got != "curl/8.0" {
+		t.Errorf("Field(http_user_agent) = %q, want %q", got, "curl/8.0")
+	}
+}
@@ -0,0 +1,46 @@
+package parser
+
+import "logagg/internal/logline"
+
+// Auto detects the format of a source from its lines. The first format that
+// understands a line is kept for the rest of the source, so an Auto must not
+// be shared between sources. Until then, lines pass through unparsed.
+type Auto struct {
+	chosen Parser
+}
+
+// detect lists the formats Auto tries, from the least to the most likely
+// to accept a line by accident.
+var detect = []struct {
+	parser Parser
+	match  func(l *logline.Line) bool
+}{
+	{JSON{}, JSON{}.Parse},
+	{combinedLog, combinedLog.Parse},
+	{commonLog, commonLog.Parse},
+	{Syslog{}, Syslog{}.Parse},
+	{Logfmt{}, strictLogfmt},
+}
+
+func (a *Auto) Parse(l *logline.Line) bool {
+	if a.chosen != nil {
+		return a.chosen.Parse(l)
+	}
+	for _, d := range detect {
+		if d.match(l) {
+			a.chosen = d.parser
+			return true
+		}
+	}
+	return false
+}
+
+func strictLogfmt(l *logline.Line) bool {
+	fields, ok := parseLogfmt(l.Raw, true)
+	if !ok {
+		return false
+	}
+	l.Fields = fields
+	common(l)
+	return true
+}
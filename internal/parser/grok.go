@@ -0,0 +1,39 @@
+package parser
+
+import (
+	"logagg/internal/grok"
+	"logagg/internal/logline"
+)
+
+// GrokPrefix introduces a format given as a grok expression, as in
+// "grok:%{TIMESTAMP_ISO8601:ts} %{LOGLEVEL:level} %{GREEDYDATA:msg}".
+const GrokPrefix = "grok:"
+
+// Patterns is the library grok expressions are compiled with. Pattern
+// files given by the user must be loaded into it before the parsers using
+// them are created.
+var Patterns = grok.NewLibrary()
+
+// Grok parses lines matching a grok expression, recording its named
+// captures as fields.
+type Grok struct {
+	g *grok.Grok
+}
+
+func NewGrok(expr string) (*Grok, error) {
+	g, err := Patterns.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &Grok{g: g}, nil
+}
+
+func (p *Grok) Parse(l *logline.Line) bool {
+	fields, ok := p.g.Match(l.Raw)
+	if !ok {
+		return false
+	}
+	l.Fields = fields
+	common(l)
+	return true
+}
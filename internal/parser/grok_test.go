@@ -0,0 +1,44 @@
+package parser
+
+import (
+	"logagg/internal/logline"
+	"testing"
+	"time"
+)
+
+func TestGrok_Parse(t *testing.T) {
+	p, err := New(`grok:^%{TIMESTAMP_ISO8601:ts} %{LOGLEVEL:level} \[%{DATA:thread}\] %{GREEDYDATA:msg}`)
+	if err != nil {
+		t.Fatalf("New() unexpected error = %v", err)
+	}
+
+	l := logline.Line{Raw: "2024-03-01 10:00:00 WARN [main] cache miss ratio=0.4"}
+	if !p.Parse(&l) {
+		t.Fatal("Parse() = false, want true")
+	}
+	if !l.Time.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.Local)) {
+		t.Errorf("Time = %v, want 2024-03-01 10:00:00 local", l.Time)
+	}
+	if l.Level != "WARN" || l.Message != "cache miss ratio=0.4" {
+		t.Errorf("unexpected level %q and message %q", l.Level, l.Message)
+	}
+	if got, _ := l.Field("thread"); got != "main" {
+		t.Errorf("Field(thread) = %q, want %q", got, "main")
+	}
+
+	other := logline.Line{Raw: "\tat com.example.Main.main(Main.java:3)"}
+	if p.Parse(&other) {
+		t.Error("expected a non matching line to be rejected")
+	}
+}
+
+func TestGrok_UnknownPattern(t *testing.T) {
+	p, err := New("grok:%{NOT_A_PATTERN:x}")
+	if err == nil {
+		t.Error("New() with unknown grok pattern error = nil, want error")
+	}
+	// A nil *Grok would make a non-nil Parser.
+	if p != nil {
+		t.Errorf("New() with unknown grok pattern = %v, want nil", p)
+	}
+}
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"logagg/internal/logline"
+	"logagg/internal/timestamp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Keys recognised as the event time, level and message, in order of
+// preference.
+var (
+	timeKeys    = []string{"time", "ts", "@timestamp", "timestamp"}
+	levelKeys   = []string{"level", "severity"}
+	messageKeys = []string{"msg", "message"}
+)
+
+// JSON parses lines holding a single JSON object. Nested objects are kept
+// as nested maps and numbers keep their original text.
+type JSON struct{}
+
+func (JSON) Parse(l *logline.Line) bool {
+	raw := strings.TrimSpace(l.Raw)
+	if !strings.HasPrefix(raw, "{") {
+		return false
+	}
+
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.UseNumber()
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil {
+		return false
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return false
+	}
+
+	l.Fields = fields
+	common(l)
+	return true
+}
+
+// common fills in the time, level and message of a parsed line from the
+// conventional keys of its fields.
+func common(l *logline.Line) {
+	for _, k := range timeKeys {
+		if t, text, ok := parseTime(l.Fields[k], l.Ingested); ok {
+			l.Time, l.TimeText = t, text
+			break
+		}
+	}
+	if v, ok := first(l.Fields, levelKeys); ok {
+		l.Level = v
+	}
+	if v, ok := first(l.Fields, messageKeys); ok {
+		l.Message = v
+	}
+}
+
+func first(fields map[string]any, keys []string) (string, bool) {
+	for _, k := range keys {
+		if v, ok := fields[k]; ok && v != nil {
+			return fmt.Sprint(v), true
+		}
+	}
+	return "", false
+}
+
+// parseTime understands RFC 3339 and the layouts of the timestamp package
+// as text, and Unix times in seconds, milliseconds, microseconds or
+// nanoseconds as numbers. Text that may lack a zone is read in local time
+// and returned, for the timestamp stage to read again in the zone of the
+// source.
+func parseTime(v any, ref time.Time) (time.Time, string, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, "", false
+		}
+		return timestamp.Epoch(f), "", true
+	case float64:
+		return timestamp.Epoch(v), "", true
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, "", true
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return timestamp.Epoch(f), "", true
+		}
+		if t, ok := timestamp.AtStart(v, time.Local, ref); ok {
+			return t, v, true
+		}
+	}
+	return time.Time{}, "", false
+}
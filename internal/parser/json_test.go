@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"logagg/internal/logline"
+	"testing"
+	"time"
+)
+
+func TestJSON_Parse(t *testing.T) {
+	l := logline.Line{Raw: `{"time":"2024-03-01T10:00:00Z","level":"error","msg":"upstream failed","http":{"status":503,"path":"/pay"}}`}
+
+	if !(JSON{}).Parse(&l) {
+		t.Fatal("Parse() = false, want true")
+	}
+
+	want := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	if !l.Time.Equal(want) {
+		t.Errorf("Time = %v, want %v", l.Time, want)
+	}
+	if l.Level != "error" {
+		t.Errorf("Level = %q, want %q", l.Level, "error")
+	}
+	if l.Message != "upstream failed" {
+		t.Errorf("Message = %q, want %q", l.Message, "upstream failed")
+	}
+	if got, _ := l.Field("http.status"); got != "503" {
+		t.Errorf("Field(http.status) = %q, want %q", got, "503")
+	}
+}
+
+func TestJSON_CommonKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		time     time.Time
+		timeText string
+		level    string
+		message  string
+	}{
+		{
+			name:    "elastic style",
+			raw:     `{"@timestamp":"2024-03-01T10:00:00.5Z","severity":"WARNING","message":"disk"}`,
+			time:    time.Date(2024, 3, 1, 10, 0, 0, 5e8, time.UTC),
+			level:   "WARNING",
+			message: "disk",
+		},
+		{
+			name: "epoch seconds",
+			raw:  `{"ts":1709287200.25}`,
+			time: time.Date(2024, 3, 1, 10, 0, 0, 25e7, time.UTC),
+		},
+		{
+			name: "epoch milliseconds",
+			raw:  `{"ts":1709287200123}`,
+			time: time.Date(2024, 3, 1, 10, 0, 0, 123e6, time.UTC),
+		},
+		{
+			name:     "no zone",
+			raw:      `{"time":"2024-03-01 10:00:00","msg":"up"}`,
+			time:     time.Date(2024, 3, 1, 10, 0, 0, 0, time.Local),
+			timeText: "2024-03-01 10:00:00",
+			message:  "up",
+		},
+		{
+			name:  "level wins over severity",
+			raw:   `{"level":"info","severity":"debug"}`,
+			level: "info",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := logline.Line{Raw: tt.raw}
+			if !(JSON{}).Parse(&l) {
+				t.Fatal("Parse() = false, want true")
+			}
+			if !l.Time.Equal(tt.time) {
+				t.Errorf("Time = %v, want %v", l.Time, tt.time)
+			}
+			if l.TimeText != tt.timeText {
+				t.Errorf("TimeText = %q, want %q", l.TimeText, tt.timeText)
+			}
+			if l.Level != tt.level {
+				t.Errorf("Level = %q, want %q", l.Level, tt.level)
+			}
+			if l.Message != tt.message {
+				t.Errorf("Message = %q, want %q", l.Message, tt.message)
+			}
+		})
+	}
+}
+
+func TestJSON_NotJSON(t *testing.T) {
+	for _, raw := range []string{
+		"plain text",
+		`{"unterminated": `,
+		`{"a":1} trailing`,
+		`[1,2,3]`,
+		``,
+	} {
+		l := logline.Line{Raw: raw}
+		if (JSON{}).Parse(&l) {
+			t.Errorf("Parse(%q) = true, want false", raw)
+		}
+		if l.Fields != nil || l.Raw != raw {
+			t.Errorf("Parse(%q) modified the line: %+v", raw, l)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"errors"
+	"logagg/internal/logline"
+	"strconv"
+	"strings"
+)
+
+// Logfmt parses key=value lines as written by Go and Heroku style loggers:
+//
+//	level=info msg="request done" dur=12ms cached
+//
+// Values may be double-quoted with Go escapes. A bare key without a value
+// is recorded as true. Lines without any key=value pair are not logfmt.
+type Logfmt struct{}
+
+func (Logfmt) Parse(l *logline.Line) bool {
+	fields, ok := parseLogfmt(l.Raw, false)
+	if !ok {
+		return false
+	}
+	l.Fields = fields
+	common(l)
+	return true
+}
+
+var errLogfmt = errors.New("logfmt inválido")
+
+// parseLogfmt splits a line into fields. With strict set the first pair
+// must have a value, which keeps ordinary sentences from being taken as a
+// list of bare keys during detection.
+func parseLogfmt(s string, strict bool) (map[string]any, bool) {
+	fields := make(map[string]any)
+	valued := 0
+	for i := 0; ; {
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+			i++
+		}
+		if i == len(s) {
+			break
+		}
+
+		start := i
+		for i < len(s) && s[i] != '=' && s[i] != ' ' && s[i] != '\t' && s[i] != '"' {
+			i++
+		}
+		key := s[start:i]
+		if key == "" {
+			return nil, false
+		}
+
+		if i == len(s) || s[i] != '=' {
+			if i < len(s) && s[i] == '"' {
+				return nil, false
+			}
+			if strict && valued == 0 {
+				return nil, false
+			}
+			fields[key] = true
+			continue
+		}
+		i++
+
+		value, n, err := logfmtValue(s[i:])
+		if err != nil {
+			return nil, false
+		}
+		fields[key] = value
+		valued++
+		i += n
+	}
+	return fields, valued > 0
+}
+
+// logfmtValue reads the value at the start of s and returns it with the
+// number of bytes consumed.
+func logfmtValue(s string) (string, int, error) {
+	if !strings.HasPrefix(s, `"`) {
+		end := strings.IndexAny(s, " \t")
+		if end < 0 {
+			end = len(s)
+		}
+		if strings.Contains(s[:end], `"`) {
+			return "", 0, errLogfmt
+		}
+		return s[:end], end, nil
+	}
+
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			if i+1 < len(s) && s[i+1] != ' ' && s[i+1] != '\t' {
+				return "", 0, errLogfmt
+			}
+			v, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", 0, errLogfmt
+			}
+			return v, i + 1, nil
+		}
+	}
+	return "", 0, errLogfmt
+}
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"logagg/internal/logline"
+	"reflect"
+	"testing"
+)
+
+func TestLogfmt_Parse(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want map[string]any
+	}{
+		{
+			raw:  `level=info msg="request done" dur=12ms`,
+			want: map[string]any{"level": "info", "msg": "request done", "dur": "12ms"},
+		},
+		{
+			raw:  `msg="say \"hi\"\n" path=C:\\tmp`,
+			want: map[string]any{"msg": "say \"hi\"\n", "path": `C:\\tmp`},
+		},
+		{
+			raw:  `cached level=debug empty= url=/a?b=c`,
+			want: map[string]any{"cached": true, "level": "debug", "empty": "", "url": "/a?b=c"},
+		},
+		{
+			raw:  "  a=1\tb=2  ",
+			want: map[string]any{"a": "1", "b": "2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			l := logline.Line{Raw: tt.raw}
+			if !(Logfmt{}).Parse(&l) {
+				t.Fatal("Parse() = false, want true")
+			}
+			if !reflect.DeepEqual(l.Fields, tt.want) {
+				t.Errorf("Fields = %v, want %v", l.Fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogfmt_CommonKeys(t *testing.T) {
+	l := logline.Line{Raw: `time=2024-03-01T10:00:00Z level=error msg="db down"`}
+	if !(Logfmt{}).Parse(&l) {
+		t.Fatal("Parse() = false, want true")
+	}
+	if l.Time.IsZero() || l.Level != "error" || l.Message != "db down" {
+		t.Errorf("expected time, level and message, got %v %q %q", l.Time, l.Level, l.Message)
+	}
+}
+
+func TestLogfmt_Invalid(t *testing.T) {
+	for _, raw := range []string{
+		"just some words",
+		`msg="unterminated`,
+		`=value`,
+		`msg="a"b`,
+		`key"x"=1`,
+		``,
+	} {
+		l := logline.Line{Raw: raw}
+		if (Logfmt{}).Parse(&l) {
+			t.Errorf("Parse(%q) = true, want false (fields %v)", raw, l.Fields)
+		}
+	}
+}
+
+func TestAuto_DetectsAndSticks(t *testing.T) {
+	a := &Auto{}
+
+	banner := logline.Line{Raw: "starting service"}
+	if a.Parse(&banner) {
+		t.Error("expected a plain line not to be detected")
+	}
+
+	first := logline.Line{Raw: `level=info msg=ready`}
+	if !a.Parse(&first) || first.Level != "info" {
+		t.Fatalf("expected logfmt to be detected, got %+v", first)
+	}
+
+	// Once logfmt is chosen a line starting with a bare key is accepted
+	// too, and JSON is no longer tried.
+	bare := logline.Line{Raw: `retrying attempt=2`}
+	if !a.Parse(&bare) || bare.Fields["attempt"] != "2" {
+		t.Errorf("expected logfmt line to parse, got %+v", bare)
+	}
+	js := logline.Line{Raw: `{"level":"error"}`}
+	if a.Parse(&js) {
+		t.Errorf("expected JSON not to be parsed once logfmt was chosen, got %+v", js)
+	}
+}
+
+func TestAuto_DoesNotTakeSentencesForLogfmt(t *testing.T) {
+	l := logline.Line{Raw: "ERROR failed to connect code=5"}
+	if (&Auto{}).Parse(&l) {
+		t.Errorf("expected plain line not to be detected as logfmt, got %v", l.Fields)
+	}
+}
@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"logagg/internal/logline"
+	"sort"
+	"strings"
+)
+
+// Parser extracts structure from the raw text of a line: fields, and when
+// the format carries them, the event time, level and message. Parse
+// reports false, leaving the line untouched, when the text is not in the
+// parser's format.
+type Parser interface {
+	Parse(l *logline.Line) bool
+}
+
+var parsers = map[string]func() Parser{
+	"auto":     func() Parser { return &Auto{} },
+	"text":     func() Parser { return Text{} },
+	"json":     func() Parser { return JSON{} },
+	"logfmt":   func() Parser { return Logfmt{} },
+	"common":   func() Parser { return commonLog },
+	"combined": func() Parser { return combinedLog },
+	"syslog":   func() Parser { return Syslog{} },
+}
+
+// Register makes a parser available to New under the given name.
+func Register(name string, factory func() Parser) {
+	parsers[name] = factory
+}
+
+// NginxPrefix introduces a custom access log format given in nginx
+// log_format syntax, as in "nginx:$remote_addr [$time_local] $status".
+const NginxPrefix = "nginx:"
+
+// New returns a fresh parser for the named log format. Parsers may keep
+// per-source state, so each source needs its own.
+func New(name string) (Parser, error) {
+	if format, ok := strings.CutPrefix(name, NginxPrefix); ok {
+		p, err := NewAccess(format)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+	if expr, ok := strings.CutPrefix(name, GrokPrefix); ok {
+		p, err := NewGrok(expr)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+	factory, ok := parsers[name]
+	if !ok {
+		return nil, fmt.Errorf("formato de log desconhecido %q: use %s, %s<log_format> ou %s<expressão>", name, strings.Join(Names(), ", "), NginxPrefix, GrokPrefix)
+	}
+	return factory(), nil
+}
+
+// Names lists the registered formats in alphabetical order.
+func Names() []string {
+	names := make([]string, 0, len(parsers))
+	for name := range parsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Text leaves lines as opaque text.
+type Text struct{}
+
+func (Text) Parse(*logline.Line) bool { return false }
+
+// Run parses every line received from in. Lines the parser does not
+// understand are forwarded unparsed rather than dropped.
+func Run(ctx context.Context, in <-chan logline.Line, p Parser) <-chan logline.Line {
+	out := make(chan logline.Line)
+
+	go func() {
+		defer close(out)
+		for l := range in {
+			p.Parse(&l)
+			select {
+			case out <- l:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
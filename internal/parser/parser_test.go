@@ -0,0 +1,41 @@
+package parser
+
+import (
+	"context"
+	"logagg/internal/logline"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	for _, name := range Names() {
+		if _, err := New(name); err != nil {
+			t.Errorf("New(%q) unexpected error = %v", name, err)
+		}
+	}
+	if _, err := New("xml"); err == nil {
+		t.Error("New() with unknown format error = nil, want error")
+	}
+}
+
+func TestRun_PassesUnparsedLinesThrough(t *testing.T) {
+	in := make(chan logline.Line, 3)
+	in <- logline.Line{Raw: `{"level":"info"}`}
+	in <- logline.Line{Raw: "not json"}
+	in <- logline.Line{Raw: `{"level":"error"}`}
+	close(in)
+
+	var got []logline.Line
+	for l := range Run(context.Background(), in, JSON{}) {
+		got = append(got, l)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(got))
+	}
+	if got[0].Level != "info" || got[2].Level != "error" {
+		t.Errorf("expected parsed levels info and error, got %q and %q", got[0].Level, got[2].Level)
+	}
+	if got[1].Raw != "not json" || got[1].Fields != nil {
+		t.Errorf("expected unparsed line unchanged, got %+v", got[1])
+	}
+}
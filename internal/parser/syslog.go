@@ -0,0 +1,198 @@
+package parser
+
+import (
+	"logagg/internal/logline"
+	"logagg/internal/timestamp"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var facilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+var severities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// severityLevels maps syslog severities onto the common level names.
+var severityLevels = []string{"fatal", "fatal", "fatal", "error", "warn", "info", "info", "debug"}
+
+// Syslog parses RFC 5424 lines and RFC 3164 lines, the latter with or
+// without the <PRI> prefix, as written to /var/log by rsyslog. The header
+// becomes the fields facility, severity, hostname, app_name, procid and
+// msgid; RFC 5424 structured data is kept under sd, one map per element,
+// so it is queried as sd.origin.ip.
+type Syslog struct {
+	// Now returns the reference time used to choose the year of RFC 3164
+	// timestamps, which have none. Nil means the time the line was read.
+	Now func() time.Time
+}
+
+func (s Syslog) Parse(l *logline.Line) bool {
+	return parse5424(l) || s.parse3164(l)
+}
+
+var rfc3164 = regexp.MustCompile(`^(?:<(\d{1,3})>)?([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}|\d{4}-\d{2}-\d{2}T\S+) (\S+) ([^\s:\[]+)(?:\[([^\]]*)\])?: ?(.*)$`)
+
+func (s Syslog) parse3164(l *logline.Line) bool {
+	m := rfc3164.FindStringSubmatch(l.Raw)
+	if m == nil {
+		return false
+	}
+	t, zoned, ok := s.time3164(m[2], l.Ingested)
+	if !ok {
+		return false
+	}
+
+	fields := map[string]any{"hostname": m[3], "app_name": m[4]}
+	if m[5] != "" {
+		fields["procid"] = m[5]
+	}
+	if m[1] != "" && !priority(l, fields, m[1]) {
+		return false
+	}
+	l.Fields = fields
+	l.Time = t
+	if !zoned {
+		l.TimeText = m[2]
+	}
+	l.Message = m[6]
+	return true
+}
+
+// time3164 reads an RFC 3164 timestamp, which has no year; see
+// timestamp.InferYear. It has no zone either, so it is read in local time
+// and zoned is false.
+func (s Syslog) time3164(v string, ingested time.Time) (t time.Time, zoned, ok bool) {
+	if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+		return t, true, true
+	}
+
+	t, err := time.ParseInLocation(time.Stamp, v, time.Local)
+	if err != nil {
+		return time.Time{}, false, false
+	}
+	ref := ingested
+	if s.Now != nil {
+		ref = s.Now()
+	}
+	return timestamp.InferYear(t, ref), false, true
+}
+
+var rfc5424 = regexp.MustCompile(`^<(\d{1,3})>(\d{1,2}) (\S+) (\S+) (\S+) (\S+) (\S+) (.*)$`)
+
+func parse5424(l *logline.Line) bool {
+	m := rfc5424.FindStringSubmatch(l.Raw)
+	if m == nil {
+		return false
+	}
+
+	fields := map[string]any{"version": m[2]}
+	if !priority(l, fields, m[1]) {
+		return false
+	}
+	for i, name := range []string{"hostname", "app_name", "procid", "msgid"} {
+		if v := m[4+i]; v != "-" {
+			fields[name] = v
+		}
+	}
+
+	var t time.Time
+	if m[3] != "-" {
+		var err error
+		if t, err = time.Parse(time.RFC3339Nano, m[3]); err != nil {
+			return false
+		}
+	}
+
+	sd, msg, ok := structuredData(m[8])
+	if !ok {
+		return false
+	}
+	if sd != nil {
+		fields["sd"] = sd
+	}
+
+	l.Fields = fields
+	l.Time = t
+	l.Message = strings.TrimPrefix(msg, "\ufeff")
+	return true
+}
+
+// priority splits a PRI value into facility and severity and sets the
+// level from the severity.
+func priority(l *logline.Line, fields map[string]any, pri string) bool {
+	n, err := strconv.Atoi(pri)
+	if err != nil || n > 191 {
+		return false
+	}
+	fields["facility"] = facilities[n/8]
+	fields["severity"] = severities[n%8]
+	l.Level = severityLevels[n%8]
+	return true
+}
+
+// structuredData parses the STRUCTURED-DATA part of an RFC 5424 line and
+// returns the message after it.
+func structuredData(s string) (map[string]any, string, bool) {
+	if s == "-" || strings.HasPrefix(s, "- ") {
+		return nil, strings.TrimPrefix(s[1:], " "), true
+	}
+
+	sd := make(map[string]any)
+	for strings.HasPrefix(s, "[") {
+		end := strings.IndexAny(s, " ]")
+		if end < 0 {
+			return nil, "", false
+		}
+		id := s[1:end]
+		params := make(map[string]any)
+		s = s[end:]
+
+		for strings.HasPrefix(s, " ") {
+			s = s[1:]
+			eq := strings.Index(s, `="`)
+			if eq <= 0 {
+				return nil, "", false
+			}
+			name := s[:eq]
+			value, n, ok := sdValue(s[eq+2:])
+			if !ok {
+				return nil, "", false
+			}
+			params[name] = value
+			s = s[eq+2+n:]
+		}
+		if !strings.HasPrefix(s, "]") {
+			return nil, "", false
+		}
+		s = s[1:]
+		sd[id] = params
+	}
+
+	if s != "" && !strings.HasPrefix(s, " ") {
+		return nil, "", false
+	}
+	return sd, strings.TrimPrefix(s, " "), true
+}
+
+// sdValue reads a PARAM-VALUE up to its closing quote, where \", \\ and
+// \] are escapes, and returns it with the bytes consumed.
+func sdValue(s string) (string, int, bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(`"\]`, s[i+1]) >= 0:
+			b.WriteByte(s[i+1])
+			i++
+		case c == '"':
+			return b.String(), i + 1, true
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, false
+}
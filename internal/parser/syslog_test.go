@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"logagg/internal/logline"
+	"testing"
+	"time"
+)
+
+func TestSyslog_RFC3164(t *testing.T) {
+	now := func() time.Time { return time.Date(2024, 3, 2, 0, 0, 0, 0, time.Local) }
+	l := logline.Line{Raw: `<34>Mar  1 10:00:00 web1 sshd[4242]: Failed password for root`}
+
+	if !(Syslog{Now: now}).Parse(&l) {
+		t.Fatal("Parse() = false, want true")
+	}
+
+	want := map[string]string{
+		"facility": "auth",
+		"severity": "crit",
+		"hostname": "web1",
+		"app_name": "sshd",
+		"procid":   "4242",
+	}
+	for k, v := range want {
+		if got, _ := l.Field(k); got != v {
+			t.Errorf("Field(%s) = %q, want %q", k, got, v)
+		}
+	}
+	if !l.Time.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.Local)) {
+		t.Errorf("Time = %v, want 2024-03-01 10:00:00 local", l.Time)
+	}
+	if l.TimeText != "Mar  1 10:00:00" {
+		t.Errorf("TimeText = %q, want the time as written", l.TimeText)
+	}
+	if l.Level != "fatal" {
+		t.Errorf("Level = %q, want %q", l.Level, "fatal")
+	}
+	if l.Message != "Failed password for root" {
+		t.Errorf("Message = %q, want %q", l.Message, "Failed password for root")
+	}
+}
+
+func TestSyslog_RFC3164WithoutPriority(t *testing.T) {
+	l := logline.Line{Raw: `Jan 15 08:30:01 db2 CRON[99]: (root) CMD (run-parts /etc/cron.hourly)`}
+	if !(Syslog{}).Parse(&l) {
+		t.Fatal("Parse() = false, want true")
+	}
+	if _, ok := l.Fields["severity"]; ok || l.Level != "" {
+		t.Errorf("expected no severity without <PRI>, got %v %q", l.Fields["severity"], l.Level)
+	}
+	if got, _ := l.Field("app_name"); got != "CRON" {
+		t.Errorf("Field(app_name) = %q, want %q", got, "CRON")
+	}
+}
+
+func TestSyslog_RFC3164Year(t *testing.T) {
+	tests := []struct {
+		name string
+		now  time.Time
+		raw  string
+		year int
+	}{
+		{"same year", time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local), "Jun  1 10:00:00 h app: x", 2024},
+		{"december read in january", time.Date(2025, 1, 1, 0, 5, 0, 0, time.Local), "Dec 31 23:59:59 h app: x", 2024},
+		{"slightly ahead clock", time.Date(2024, 12, 31, 23, 0, 0, 0, time.Local), "Dec 31 23:30:00 h app: x", 2024},
+		{"leap day", time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local), "Feb 29 12:00:00 h app: x", 2024},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := logline.Line{Raw: tt.raw}
+			if !(Syslog{Now: func() time.Time { return tt.now }}).Parse(&l) {
+				t.Fatal("Parse() = false, want true")
+			}
+			if l.Time.Year() != tt.year {
+				t.Errorf("year = %d, want %d (%v)", l.Time.Year(), tt.year, l.Time)
+			}
+			if tt.name == "leap day" && l.Time.Day() != 29 {
+				t.Errorf("expected February 29th to be kept, got %v", l.Time)
+			}
+		})
+	}
+}
+
+func TestSyslog_RFC5424(t *testing.T) {
+	l := logline.Line{Raw: `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Appli\"cation" eventID="1011"][origin ip="192.0.2.1"] ` + "\ufeff" + `An application event`}
+
+	if !(Syslog{}).Parse(&l) {
+		t.Fatal("Parse() = false, want true")
+	}
+
+	want := map[string]string{
+		"facility":                         "local4",
+		"severity":                         "notice",
+		"hostname":                         "mymachine.example.com",
+		"app_name":                         "evntslog",
+		"msgid":                            "ID47",
+		"sd.exampleSDID@32473.eventSource": `Appli"cation`,
+		"sd.origin.ip":                     "192.0.2.1",
+	}
+	for k, v := range want {
+		if got, _ := l.Field(k); got != v {
+			t.Errorf("Field(%s) = %q, want %q", k, got, v)
+		}
+	}
+	if _, ok := l.Fields["procid"]; ok {
+		t.Error("expected nil procid to be left out")
+	}
+	if !l.Time.Equal(time.Date(2003, 10, 11, 22, 14, 15, 3e6, time.UTC)) {
+		t.Errorf("Time = %v, want 2003-10-11 22:14:15.003 UTC", l.Time)
+	}
+	if l.Level != "info" {
+		t.Errorf("Level = %q, want %q", l.Level, "info")
+	}
+	if l.Message != "An application event" {
+		t.Errorf("Message = %q, want %q", l.Message, "An application event")
+	}
+}
+
+func TestSyslog_RFC5424WithoutStructuredData(t *testing.T) {
+	l := logline.Line{Raw: `<11>1 - host app 77 - - disk full`}
+	if !(Syslog{}).Parse(&l) {
+		t.Fatal("Parse() = false, want true")
+	}
+	if l.Message != "disk full" || l.Level != "error" || !l.Time.IsZero() {
+		t.Errorf("unexpected result: message %q level %q time %v", l.Message, l.Level, l.Time)
+	}
+	if _, ok := l.Fields["sd"]; ok {
+		t.Error("expected no sd field")
+	}
+}
+
+func TestSyslog_Invalid(t *testing.T) {
+	for _, raw := range []string{
+		"plain text",
+		`<999>Mar  1 10:00:00 web1 sshd: x`,
+		`<34>1 2003-10-11T22:14:15Z h a - - [unterminated x="1"`,
+		`<34>1 not-a-time h a - - - msg`,
+	} {
+		l := logline.Line{Raw: raw}
+		if (Syslog{}).Parse(&l) {
+			t.Errorf("Parse(%q) = true, want false", raw)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package query
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Record is what a query is evaluated against.
+type Record interface {
+	// Text is the content searched by bare terms.
+	Text() string
+	// Field returns the value of a named field, if the record has it.
+	Field(name string) (string, bool)
+}
+
+// Node is a parsed query expression.
+type Node interface {
+	Eval(r Record) bool
+	String() string
+}
+
+type And struct {
+	Left, Right Node
+}
+
+func (n *And) Eval(r Record) bool { return n.Left.Eval(r) && n.Right.Eval(r) }
+
+func (n *And) String() string { return "(" + n.Left.String() + " AND " + n.Right.String() + ")" }
+
+type Or struct {
+	Left, Right Node
+}
+
+func (n *Or) Eval(r Record) bool { return n.Left.Eval(r) || n.Right.Eval(r) }
+
+func (n *Or) String() string { return "(" + n.Left.String() + " OR " + n.Right.String() + ")" }
+
+type Not struct {
+	X Node
+}
+
+func (n *Not) Eval(r Record) bool { return !n.X.Eval(r) }
+
+func (n *Not) String() string { return "NOT " + n.X.String() }
+
+// Contains matches records whose text contains Value.
+type Contains struct {
+	Value string
+}
+
+func (n *Contains) Eval(r Record) bool { return strings.Contains(r.Text(), n.Value) }
+
+func (n *Contains) String() string { return strconv.Quote(n.Value) }
+
+// Equal matches records whose field equals Value, ignoring case.
+type Equal struct {
+	Field string
+	Value string
+}
+
+func (n *Equal) Eval(r Record) bool {
+	v, ok := r.Field(n.Field)
+	return ok && strings.EqualFold(v, n.Value)
+}
+
+func (n *Equal) String() string { return n.Field + ":" + strconv.Quote(n.Value) }
+
+// Regex matches records whose field matches a regular expression.
+type Regex struct {
+	Field string
+	Re    *regexp.Regexp
+}
+
+func (n *Regex) Eval(r Record) bool {
+	v, ok := r.Field(n.Field)
+	return ok && n.Re.MatchString(v)
+}
+
+func (n *Regex) String() string { return n.Field + "~" + strconv.Quote(n.Re.String()) }
+
+// Compare matches records whose field compares to Value with Op, one of
+// =, !=, >, >=, < and <=. When both sides are numbers they are compared as
+// numbers; otherwise as text, ignoring case. Records without the field
+// never match.
+type Compare struct {
+	Field string
+	Op    string
+	Value string
+}
+
+func (n *Compare) Eval(r Record) bool {
+	v, ok := r.Field(n.Field)
+	if !ok {
+		return false
+	}
+	c := compare(v, n.Value)
+	switch n.Op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+	return false
+}
+
+func (n *Compare) String() string { return n.Field + n.Op + strconv.Quote(n.Value) }
+
+func compare(a, b string) int {
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
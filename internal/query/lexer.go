@@ -0,0 +1,175 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+	tokColon
+	tokTilde
+	tokCompare
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokEOF:
+		return "fim da consulta"
+	case tokWord:
+		return "palavra"
+	case tokString:
+		return "texto entre aspas"
+	case tokAnd:
+		return "AND"
+	case tokOr:
+		return "OR"
+	case tokNot:
+		return "NOT"
+	case tokLParen:
+		return "'('"
+	case tokRParen:
+		return "')'"
+	case tokColon:
+		return "':'"
+	case tokTilde:
+		return "'~'"
+	case tokCompare:
+		return "comparação"
+	default:
+		return "?"
+	}
+}
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// SyntaxError reports a problem in a query together with the byte offset
+// where it was found.
+type SyntaxError struct {
+	Query string
+	Pos   int
+	Msg   string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("consulta inválida na coluna %d: %s", e.Pos+1, e.Msg)
+}
+
+// Context returns the query with a caret under the offending position,
+// ready to be printed below the error message.
+func (e *SyntaxError) Context() string {
+	return e.Query + "\n" + strings.Repeat(" ", e.Pos) + "^"
+}
+
+func isSpecial(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '(', ')', ':', '~', '"', '=', '<', '>':
+		return true
+	}
+	return false
+}
+
+// compareOp returns the comparison operator starting at src[i], if any.
+// A lone '!' is part of a word; only "!=" is an operator.
+func compareOp(src string, i int) string {
+	switch src[i] {
+	case '=':
+		return "="
+	case '<', '>', '!':
+		if i+1 < len(src) && src[i+1] == '=' {
+			return src[i : i+2]
+		}
+		if src[i] != '!' {
+			return src[i : i+1]
+		}
+	}
+	return ""
+}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			toks = append(toks, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			toks = append(toks, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case c == ':':
+			toks = append(toks, token{kind: tokColon, text: ":", pos: i})
+			i++
+		case c == '~':
+			toks = append(toks, token{kind: tokTilde, text: "~", pos: i})
+			i++
+		case compareOp(src, i) != "":
+			op := compareOp(src, i)
+			toks = append(toks, token{kind: tokCompare, text: op, pos: i})
+			i += len(op)
+		case c == '"':
+			text, n, err := lexString(src, i)
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, token{kind: tokString, text: text, pos: i})
+			i += n
+		default:
+			start := i
+			for i < len(src) && !isSpecial(src[i]) && compareOp(src, i) == "" {
+				i++
+			}
+			word := src[start:i]
+			kind := tokWord
+			switch word {
+			case "AND":
+				kind = tokAnd
+			case "OR":
+				kind = tokOr
+			case "NOT":
+				kind = tokNot
+			}
+			toks = append(toks, token{kind: kind, text: word, pos: start})
+		}
+	}
+	return append(toks, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+// lexString reads a double-quoted string starting at src[start] and returns
+// its unescaped content and the number of bytes consumed. Only \" and \\
+// are escapes; any other backslash is kept so regular expressions such as
+// "\d+" can be written without doubling it.
+func lexString(src string, start int) (string, int, error) {
+	var b strings.Builder
+	i := start + 1
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == '"':
+			return b.String(), i + 1 - start, nil
+		case c == '\\' && i+1 < len(src) && (src[i+1] == '"' || src[i+1] == '\\'):
+			b.WriteByte(src[i+1])
+			i += 2
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return "", 0, &SyntaxError{Query: src, Pos: start, Msg: "aspas sem fechamento"}
+}
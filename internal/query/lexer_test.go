@@ -0,0 +1,85 @@
+package query
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLex(t *testing.T) {
+	toks, err := lex(`level:error AND (msg~"time\"out" OR NOT x)`)
+	if err != nil {
+		t.Fatalf("lex() unexpected error = %v", err)
+	}
+
+	expected := []struct {
+		kind tokenKind
+		text string
+		pos  int
+	}{
+		{tokWord, "level", 0},
+		{tokColon, ":", 5},
+		{tokWord, "error", 6},
+		{tokAnd, "AND", 12},
+		{tokLParen, "(", 16},
+		{tokWord, "msg", 17},
+		{tokTilde, "~", 20},
+		{tokString, `time"out`, 21},
+		{tokOr, "OR", 33},
+		{tokNot, "NOT", 36},
+		{tokWord, "x", 40},
+		{tokRParen, ")", 41},
+		{tokEOF, "", 42},
+	}
+
+	if len(toks) != len(expected) {
+		t.Fatalf("expected %d tokens, got %d: %v", len(expected), len(toks), toks)
+	}
+	for i, exp := range expected {
+		if toks[i].kind != exp.kind || toks[i].text != exp.text || toks[i].pos != exp.pos {
+			t.Errorf("token %d: expected %v %q at %d, got %v %q at %d",
+				i, exp.kind, exp.text, exp.pos, toks[i].kind, toks[i].text, toks[i].pos)
+		}
+	}
+}
+
+func TestLex_Comparisons(t *testing.T) {
+	toks, err := lex(`http.status>=500 a!=b c<d wow!`)
+	if err != nil {
+		t.Fatalf("lex() unexpected error = %v", err)
+	}
+
+	want := []string{"http.status", ">=", "500", "a", "!=", "b", "c", "<", "d", "wow!", ""}
+	if len(toks) != len(want) {
+		t.Fatalf("expected %d tokens, got %d: %v", len(want), len(toks), toks)
+	}
+	for i, w := range want {
+		if toks[i].text != w {
+			t.Errorf("token %d: expected %q, got %q", i, w, toks[i].text)
+		}
+	}
+	if toks[1].kind != tokCompare || toks[9].kind != tokWord {
+		t.Errorf("expected >= to be a comparison and wow! a word, got %v and %v", toks[1].kind, toks[9].kind)
+	}
+}
+
+func TestLex_KeepsRegexBackslashes(t *testing.T) {
+	toks, err := lex(`"\d+\\"`)
+	if err != nil {
+		t.Fatalf("lex() unexpected error = %v", err)
+	}
+	if toks[0].text != `\d+\` {
+		t.Errorf("expected %q, got %q", `\d+\`, toks[0].text)
+	}
+}
+
+func TestLex_UnterminatedString(t *testing.T) {
+	_, err := lex(`msg~"timeout`)
+
+	var serr *SyntaxError
+	if !errors.As(err, &serr) {
+		t.Fatalf("lex() error = %v, want *SyntaxError", err)
+	}
+	if serr.Pos != 4 {
+		t.Errorf("expected error at 4, got %d", serr.Pos)
+	}
+}
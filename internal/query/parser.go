@@ -0,0 +1,147 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Parse turns a query into its syntax tree. The grammar is:
+//
+//	expr    = or
+//	or      = and { "OR" and }
+//	and     = unary { "AND" unary }
+//	unary   = "NOT" unary | primary
+//	primary = "(" expr ")" | term
+//	term    = value | field ":" value | field "~" value | field cmp value
+//	cmp     = "=" | "!=" | ">" | ">=" | "<" | "<="
+//	value   = word | string
+//
+// A bare value matches records whose text contains it, field:value
+// compares a field ignoring case and field~value matches a field against a
+// regular expression. The comparison operators compare numerically when
+// both sides are numbers, so http.status>=500 works on parsed fields.
+func Parse(src string) (Node, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: src, toks: toks}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "esperado AND, OR ou fim da consulta, encontrado %q", t.text)
+	}
+	return n, nil
+}
+
+type parser struct {
+	src  string
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return &SyntaxError{Query: p.src, Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{X: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, p.errorf(closing, "esperado ')' para fechar o '(' da coluna %d", t.pos+1)
+		}
+		return n, nil
+	case tokString:
+		return &Contains{Value: t.text}, nil
+	case tokWord:
+		return p.parseTerm(t)
+	default:
+		return nil, p.errorf(t, "esperado um termo, encontrado %s", t.kind)
+	}
+}
+
+func (p *parser) parseTerm(field token) (Node, error) {
+	op := p.peek()
+	if op.kind != tokColon && op.kind != tokTilde && op.kind != tokCompare {
+		return &Contains{Value: field.text}, nil
+	}
+	p.next()
+
+	v := p.next()
+	if v.kind != tokWord && v.kind != tokString {
+		return nil, p.errorf(v, "esperado um valor depois de %q%s, encontrado %s", field.text, op.text, v.kind)
+	}
+
+	switch op.kind {
+	case tokColon:
+		return &Equal{Field: field.text, Value: v.text}, nil
+	case tokCompare:
+		return &Compare{Field: field.text, Op: op.text, Value: v.text}, nil
+	}
+	re, err := regexp.Compile(v.text)
+	if err != nil {
+		return nil, p.errorf(v, "expressão regular inválida: %v", err)
+	}
+	return &Regex{Field: field.text, Re: re}, nil
+}
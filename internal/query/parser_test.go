@@ -0,0 +1,221 @@
+package query
+
+import (
+	"errors"
+	"logagg/internal/level"
+	"logagg/internal/logline"
+	logparser "logagg/internal/parser"
+	"strings"
+	"testing"
+)
+
+type fakeRecord struct {
+	text   string
+	fields map[string]string
+}
+
+func (r fakeRecord) Text() string { return r.text }
+
+func (r fakeRecord) Field(name string) (string, bool) {
+	v, ok := r.fields[name]
+	return v, ok
+}
+
+func TestParse_Precedence(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`a OR b AND c`, `("a" OR ("b" AND "c"))`},
+		{`(a OR b) AND c`, `(("a" OR "b") AND "c")`},
+		{`NOT a AND b`, `(NOT "a" AND "b")`},
+		{`NOT NOT a`, `NOT NOT "a"`},
+		{`level:error`, `level:"error"`},
+		{`msg~"time(out)?"`, `msg~"time(out)?"`},
+		{`http.status>=500 AND level=error`, `(http.status>="500" AND level="error")`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			n, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse() unexpected error = %v", err)
+			}
+			if got := n.String(); got != tt.want {
+				t.Errorf("Parse() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Eval(t *testing.T) {
+	q := `level:error AND (service:payments OR msg~"timeout") AND NOT source:healthz.log`
+
+	tests := []struct {
+		name   string
+		record fakeRecord
+		want   bool
+	}{
+		{
+			name:   "payments error",
+			record: fakeRecord{fields: map[string]string{"level": "ERROR", "service": "payments", "source": "app.log", "msg": "declined"}},
+			want:   true,
+		},
+		{
+			name:   "timeout in another service",
+			record: fakeRecord{fields: map[string]string{"level": "error", "service": "orders", "source": "app.log", "msg": "upstream timeout"}},
+			want:   true,
+		},
+		{
+			name:   "excluded source",
+			record: fakeRecord{fields: map[string]string{"level": "error", "service": "payments", "source": "healthz.log"}},
+			want:   false,
+		},
+		{
+			name:   "wrong level",
+			record: fakeRecord{fields: map[string]string{"level": "info", "service": "payments", "source": "app.log"}},
+			want:   false,
+		},
+		{
+			name:   "missing fields",
+			record: fakeRecord{fields: map[string]string{"level": "error", "source": "app.log"}},
+			want:   false,
+		},
+	}
+
+	n, err := Parse(q)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := n.Eval(tt.record); got != tt.want {
+				t.Errorf("Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_BareTermsSearchText(t *testing.T) {
+	n, err := Parse(`"connection refused" AND NOT retry`)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error = %v", err)
+	}
+
+	if !n.Eval(fakeRecord{text: "dial tcp: connection refused"}) {
+		t.Error("expected match on text containing the phrase")
+	}
+	if n.Eval(fakeRecord{text: "connection refused, will retry"}) {
+		t.Error("expected no match when the negated term is present")
+	}
+}
+
+func TestParse_Compare(t *testing.T) {
+	tests := []struct {
+		query string
+		value string
+		want  bool
+	}{
+		{`status>=500`, "503", true},
+		{`status>=500`, "404", false},
+		{`status>=500`, "1000", true},
+		{`status<500`, "99", true},
+		{`status=200`, "200.0", true},
+		{`status!=200`, "201", true},
+		{`level=error`, "ERROR", true},
+		{`level!=error`, "warn", true},
+		{`dur>"1.5"`, "1.25", false},
+		{`host<b`, "alpha", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query+" "+tt.value, func(t *testing.T) {
+			n, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse() unexpected error = %v", err)
+			}
+			field := n.(*Compare).Field
+			r := fakeRecord{fields: map[string]string{field: tt.value}}
+			if got := n.Eval(r); got != tt.want {
+				t.Errorf("Eval(%s=%q) = %v, want %v", field, tt.value, got, tt.want)
+			}
+		})
+	}
+
+	n, _ := Parse(`status!=200`)
+	if n.Eval(fakeRecord{}) {
+		t.Error("expected no match when the field is missing")
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+		msg   string
+	}{
+		{`level:error AND`, 15, "esperado um termo"},
+		{`(level:error`, 12, "esperado ')'"},
+		{`level:`, 6, "esperado um valor"},
+		{`level:error)`, 11, "esperado AND, OR"},
+		{`msg~"(unclosed"`, 4, "expressão regular inválida"},
+		{`a OR OR b`, 5, "esperado um termo"},
+		{`status>=`, 8, "esperado um valor"},
+		{`>=500`, 0, "esperado um termo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := Parse(tt.query)
+
+			var serr *SyntaxError
+			if !errors.As(err, &serr) {
+				t.Fatalf("Parse() error = %v, want *SyntaxError", err)
+			}
+			if serr.Pos != tt.pos {
+				t.Errorf("expected error at %d, got %d (%v)", tt.pos, serr.Pos, err)
+			}
+			if !strings.Contains(serr.Msg, tt.msg) {
+				t.Errorf("expected message containing %q, got %q", tt.msg, serr.Msg)
+			}
+		})
+	}
+}
+
+func TestSyntaxError_Context(t *testing.T) {
+	_, err := Parse(`level:error AND`)
+
+	var serr *SyntaxError
+	if !errors.As(err, &serr) {
+		t.Fatalf("Parse() error = %v, want *SyntaxError", err)
+	}
+	want := "level:error AND\n               ^"
+	if got := serr.Context(); got != want {
+		t.Errorf("Context() = %q, want %q", got, want)
+	}
+}
+
+func TestParse_EvalNormalisedLevel(t *testing.T) {
+	n, err := Parse(`level:warn OR level:error`)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error = %v", err)
+	}
+	p, err := logparser.New("json")
+	if err != nil {
+		t.Fatalf("New() unexpected error = %v", err)
+	}
+
+	for _, raw := range []string{`{"level":"WARNING","msg":"slow"}`, `{"severity":"err","msg":"down"}`, `{"level":50,"msg":"failed"}`} {
+		l := logline.Line{Raw: raw}
+		if !p.Parse(&l) {
+			t.Fatalf("expected %s to parse", raw)
+		}
+		// As the level stage does.
+		if lv, ok := level.Of(l); ok {
+			l.Level = lv.String()
+		}
+		if !n.Eval(l) {
+			t.Errorf("expected %s to match", raw)
+		}
+	}
+}
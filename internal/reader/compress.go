@@ -0,0 +1,93 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Compression identifies the format of a compressed log file.
+type Compression int
+
+const (
+	Uncompressed Compression = iota
+	Gzip
+	Bzip2
+	Zstd
+	Xz
+)
+
+func (c Compression) String() string {
+	switch c {
+	case Gzip:
+		return "gzip"
+	case Bzip2:
+		return "bzip2"
+	case Zstd:
+		return "zstd"
+	case Xz:
+		return "xz"
+	default:
+		return "nenhuma"
+	}
+}
+
+var magics = []struct {
+	c     Compression
+	magic []byte
+}{
+	{Gzip, []byte{0x1f, 0x8b}},
+	{Bzip2, []byte("BZh")},
+	{Zstd, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{Xz, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+}
+
+// maxMagic is how many bytes detectCompression needs to look at.
+const maxMagic = 6
+
+// detectCompression recognises a compressed file by its magic bytes, so
+// rotated files are decompressed whatever their extension.
+func detectCompression(header []byte) Compression {
+	for _, m := range magics {
+		if bytes.HasPrefix(header, m.magic) {
+			return m.c
+		}
+	}
+	return Uncompressed
+}
+
+// decompress wraps r with a decoder for c. The returned reader must be
+// closed to release the decoder; closing it does not close r.
+func decompress(c Compression, r io.Reader) (io.ReadCloser, error) {
+	switch c {
+	case Gzip:
+		return gzip.NewReader(r)
+	case Bzip2:
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	case Zstd:
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	case Xz:
+		d, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(d), nil
+	default:
+		return io.NopCloser(r), nil
+	}
+}
+
+// sniff peeks at the start of br and returns its compression format.
+func sniff(br *bufio.Reader) Compression {
+	header, _ := br.Peek(maxMagic)
+	return detectCompression(header)
+}
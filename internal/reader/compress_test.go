@@ -0,0 +1,139 @@
+package reader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// bzip2 of "line 1\nline 2\n"; the standard library can only decompress it.
+var bzip2Sample = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x31, 0x88,
+	0x21, 0x68, 0x00, 0x00, 0x05, 0x59, 0x00, 0x00, 0x10, 0x40, 0x00, 0x30,
+	0x00, 0x02, 0x25, 0x20, 0x00, 0x31, 0x0c, 0x08, 0x12, 0x86, 0x46, 0x89,
+	0x31, 0x90, 0x87, 0x10, 0xf1, 0x77, 0x24, 0x53, 0x85, 0x09, 0x03, 0x18,
+	0x82, 0x16, 0x80,
+}
+
+func gzipBytes(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(s))
+	w.Close()
+	return buf.Bytes()
+}
+
+func zstdBytes(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("failed to create zstd writer: %v", err)
+	}
+	w.Write([]byte(s))
+	w.Close()
+	return buf.Bytes()
+}
+
+func xzBytes(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := xz.NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("failed to create xz writer: %v", err)
+	}
+	w.Write([]byte(s))
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestDetectCompression(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []byte
+		want   Compression
+	}{
+		{"gzip", []byte{0x1f, 0x8b, 0x08}, Gzip},
+		{"bzip2", []byte("BZh91AY"), Bzip2},
+		{"zstd", []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00}, Zstd},
+		{"xz", []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, Xz},
+		{"plain text", []byte("2024-01-15 INFO"), Uncompressed},
+		{"empty", nil, Uncompressed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectCompression(tt.header); got != tt.want {
+				t.Errorf("detectCompression() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRead_CompressedFiles(t *testing.T) {
+	content := "line 1\nline 2\n"
+	tests := []struct {
+		name string
+		data func(t *testing.T) []byte
+	}{
+		{"app.log.1.gz", func(t *testing.T) []byte { return gzipBytes(t, content) }},
+		{"app.log.2.bz2", func(t *testing.T) []byte { return bzip2Sample }},
+		{"app.log.3.zst", func(t *testing.T) []byte { return zstdBytes(t, content) }},
+		{"app.log.4.xz", func(t *testing.T) []byte { return xzBytes(t, content) }},
+		// Detection relies on magic bytes, not on the extension.
+		{"app.log.5", func(t *testing.T) []byte { return gzipBytes(t, content) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.name)
+			if err := os.WriteFile(path, tt.data(t), 0o644); err != nil {
+				t.Fatalf("failed to write %s: %v", path, err)
+			}
+
+			if err := ValidateFile(path); err != nil {
+				t.Fatalf("ValidateFile() unexpected error = %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			// Tail mode is ignored for compressed files, so the channel
+			// closes once the content is read.
+			var lines []string
+			for l := range Read(ctx, path, Options{Tail: true, PollInterval: testPoll}) {
+				lines = append(lines, l.Raw)
+			}
+			if ctx.Err() != nil {
+				t.Fatal("reader kept following a compressed file")
+			}
+
+			if len(lines) != 2 || lines[0] != "line 1" || lines[1] != "line 2" {
+				t.Errorf("expected decompressed lines, got %q", lines)
+			}
+		})
+	}
+}
+
+func TestValidateFile_CorruptCompressedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.gz")
+	if err := os.WriteFile(path, []byte{0x1f, 0x8b, 0x00, 0x00}, 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+
+	err := ValidateFile(path)
+	if err == nil {
+		t.Fatal("ValidateFile() error = nil, want error for corrupt gzip")
+	}
+	if !contains(err.Error(), "arquivo compactado (gzip) inválido") {
+		t.Errorf("ValidateFile() error = %v, want error mentioning the compression", err)
+	}
+}
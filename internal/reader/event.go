@@ -0,0 +1,77 @@
+package reader
+
+import (
+	"context"
+	"fmt"
+)
+
+// EventKind identifies something that happened to a source while it was
+// being read.
+type EventKind int
+
+const (
+	EventRotated EventKind = iota
+	EventTruncated
+	// EventError reports a failure; Event.Err holds a *SourceError.
+	EventError
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventRotated:
+		return "rotacionado"
+	case EventTruncated:
+		return "truncado"
+	case EventError:
+		return "com erro"
+	default:
+		return "desconhecido"
+	}
+}
+
+// Event reports a change in a followed source, such as logrotate moving
+// the file away or a copytruncate shrinking it, or a failure reading it.
+type Event struct {
+	Kind   EventKind
+	Source string
+	Err    error
+}
+
+// Op names the operation that failed in a SourceError.
+type Op string
+
+const (
+	OpOpen   Op = "abrir"
+	OpRead   Op = "ler"
+	OpStat   Op = "verificar"
+	OpReopen Op = "reabrir"
+	OpRewind Op = "voltar ao início de"
+)
+
+// SourceError is a failure of a single source. The reader of that source
+// stops after open and read errors; the rest of the pipeline keeps going.
+type SourceError struct {
+	Source string
+	Op     Op
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("erro ao %s %s: %v", e.Op, e.Source, e.Err)
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+func notify(ctx context.Context, events chan<- Event, ev Event) bool {
+	if events == nil {
+		return true
+	}
+	select {
+	case events <- ev:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
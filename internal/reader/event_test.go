@@ -0,0 +1,68 @@
+package reader
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRead_OpenErrorIsReported(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.log")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 1)
+	ch := Read(ctx, path, Options{Events: events})
+
+	select {
+	case ev := <-events:
+		if ev.Kind != EventError {
+			t.Fatalf("expected event %v, got %v", EventError, ev.Kind)
+		}
+		if ev.Source != path {
+			t.Errorf("expected source %q, got %q", path, ev.Source)
+		}
+		var serr *SourceError
+		if !errors.As(ev.Err, &serr) {
+			t.Fatalf("expected *SourceError, got %T", ev.Err)
+		}
+		if serr.Op != OpOpen {
+			t.Errorf("expected op %q, got %q", OpOpen, serr.Op)
+		}
+		if !errors.Is(ev.Err, os.ErrNotExist) {
+			t.Errorf("expected error to wrap os.ErrNotExist, got %v", ev.Err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for error event")
+	}
+
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("expected channel to be closed after an open error")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for channel to close")
+	}
+}
+
+func TestRead_OpenErrorWithoutEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.log")
+
+	// Without an events channel the reader must still end instead of
+	// exiting the process.
+	for range ReadLines(context.Background(), path, false) {
+		t.Error("expected no lines from a missing file")
+	}
+}
+
+func TestSourceError_Error(t *testing.T) {
+	err := &SourceError{Source: "app.log", Op: OpRead, Err: errors.New("falha")}
+	if got, want := err.Error(), "erro ao ler app.log: falha"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
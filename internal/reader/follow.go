@@ -0,0 +1,345 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"logagg/internal/checkpoint"
+	"os"
+	"unicode/utf8"
+)
+
+// follower reads complete lines from a file and, in tail mode, keeps
+// following the path by name: when the file behind the path is replaced
+// or shrinks it notices and starts over on the new content.
+type follower struct {
+	path string
+	f    *os.File
+	info os.FileInfo
+	dec  io.Closer
+	r    *bufio.Reader
+	// compression is the format of the open file. Compressed files are
+	// read once and never followed.
+	compression Compression
+	// offset is where the line being read starts, or the next chunk of a
+	// line being split.
+	offset  int64
+	number  int64
+	partial []byte
+
+	// limit bounds partial. skipped counts bytes of the current line read
+	// but dropped, cut tells the limit was exceeded and the rest of the line
+	// is being discarded, and split that part of it was already emitted.
+	// eol is set once the terminator of the buffered line was read.
+	limit   LineLimit
+	skipped int64
+	cut     bool
+	split   bool
+	eol     bool
+
+	// seeked is set when reading started at a position found by seekTime,
+	// so line numbers are not known.
+	seeked bool
+
+	// stores supply the position to resume each opened file from, the
+	// first one that knows the file winning; id identifies the open file
+	// in them.
+	stores []*checkpoint.Store
+	id     checkpoint.Identity
+}
+
+// rawLine is a line as found in the file, before it becomes a
+// logline.Line.
+type rawLine struct {
+	text   string
+	offset int64
+	end    int64
+	number int64
+}
+
+func openFollower(path string, limit LineLimit, stores ...*checkpoint.Store) (*follower, error) {
+	fl := &follower{path: path, limit: limit}
+	for _, s := range stores {
+		if s != nil {
+			fl.stores = append(fl.stores, s)
+		}
+	}
+	if err := fl.open(); err != nil {
+		return nil, err
+	}
+	return fl, nil
+}
+
+func (fl *follower) open() error {
+	f, err := os.Open(fl.path)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	br := bufio.NewReader(f)
+	c := sniff(br)
+	var dec io.ReadCloser
+	if c != Uncompressed {
+		if dec, err = decompress(c, br); err != nil {
+			f.Close()
+			return err
+		}
+		br = bufio.NewReader(dec)
+	}
+
+	fl.f = f
+	fl.info = info
+	fl.dec = dec
+	fl.r = br
+	fl.compression = c
+	fl.reset()
+
+	for _, s := range fl.stores {
+		id, e, ok := s.Lookup(f)
+		fl.id = id
+		if ok {
+			return fl.resume(e)
+		}
+	}
+	return nil
+}
+
+// resume skips what a previous run already emitted. A file that became
+// smaller than the saved offset was truncated meanwhile and is read from
+// the start. A saved line number of 0 past the start means the previous
+// run had seeked, so line numbers stay unknown.
+func (fl *follower) resume(e checkpoint.Entry) error {
+	if fl.compression != Uncompressed {
+		if _, err := io.CopyN(io.Discard, fl.r, e.Offset); err != nil {
+			return err
+		}
+	} else {
+		if e.Offset > fl.info.Size() {
+			return nil
+		}
+		if _, err := fl.f.Seek(e.Offset, io.SeekStart); err != nil {
+			return err
+		}
+		fl.r.Reset(fl.f)
+	}
+	fl.offset = e.Offset
+	fl.number = e.Line
+	fl.seeked = e.Line == 0 && e.Offset > 0
+	return nil
+}
+
+func (fl *follower) close() error {
+	if fl.dec != nil {
+		fl.dec.Close()
+	}
+	return fl.f.Close()
+}
+
+// readLine returns the next complete line without its terminator. When the
+// end of the file is reached in the middle of a line, the partial content
+// is kept and io.EOF is returned, so a writer finishing the line later does
+// not split it in two. Lines over the limit are handled by its policy.
+func (fl *follower) readLine() (rawLine, error) {
+	for {
+		if line, ok := fl.chunk(); ok {
+			return line, nil
+		}
+		if fl.eol {
+			fl.eol = false
+			if line, ok := fl.end(); ok {
+				return line, nil
+			}
+			continue
+		}
+
+		data, err := fl.r.ReadSlice('\n')
+		fl.add(data)
+		switch {
+		case err == nil:
+			fl.eol = true
+		case errors.Is(err, bufio.ErrBufferFull):
+		default:
+			if line, ok := fl.chunk(); ok {
+				return line, nil
+			}
+			return rawLine{}, err
+		}
+	}
+}
+
+// add buffers data read from the current line, dropping what goes beyond
+// the limit unless the line is being split.
+func (fl *follower) add(data []byte) {
+	if fl.cut {
+		fl.skipped += int64(len(data))
+		return
+	}
+	fl.partial = append(fl.partial, data...)
+
+	max := fl.limit.max()
+	policy := fl.limit.policy()
+	if policy == LongLineSplit || len(trimEOL(fl.partial)) <= max {
+		return
+	}
+	keep := runeBoundary(fl.partial, max)
+	if policy == LongLineSkip {
+		keep = 0
+	}
+	fl.skipped += int64(len(fl.partial) - keep)
+	fl.partial = fl.partial[:keep]
+	fl.cut = true
+	fl.limit.count()
+}
+
+// chunk returns the next piece of a line being split, once more than the
+// limit is buffered.
+func (fl *follower) chunk() (rawLine, bool) {
+	max := fl.limit.max()
+	if fl.limit.policy() != LongLineSplit || len(trimEOL(fl.partial)) <= max {
+		return rawLine{}, false
+	}
+	if !fl.split {
+		fl.number++
+		fl.split = true
+		fl.limit.count()
+	}
+	n := runeBoundary(fl.partial, max)
+	line := rawLine{text: string(fl.partial[:n]), offset: fl.offset, number: fl.number}
+	fl.offset += int64(n)
+	line.end = fl.offset
+	fl.partial = append([]byte(nil), fl.partial[n:]...)
+	return line, true
+}
+
+// end finishes the current line once its terminator was read. It reports
+// false for a line dropped by LongLineSkip.
+func (fl *follower) end() (rawLine, bool) {
+	if fl.cut && fl.limit.policy() == LongLineSkip {
+		fl.number++
+		fl.offset += fl.skipped
+		fl.skipped = 0
+		fl.cut = false
+		return rawLine{}, false
+	}
+	return fl.take(), true
+}
+
+// flush returns the trailing content of the file that was not terminated
+// by a newline.
+func (fl *follower) flush() (rawLine, bool) {
+	if len(fl.partial) == 0 && !fl.cut {
+		return rawLine{}, false
+	}
+	return fl.end()
+}
+
+// take turns the buffered bytes into a line and advances past them.
+func (fl *follower) take() rawLine {
+	if !fl.split {
+		fl.number++
+	}
+	text := string(trimEOL(fl.partial))
+	if fl.cut {
+		text += TruncatedMarker
+	}
+	line := rawLine{text: text, offset: fl.offset, number: fl.number}
+	fl.offset += fl.skipped + int64(len(fl.partial))
+	line.end = fl.offset
+	fl.partial = nil
+	fl.skipped = 0
+	fl.cut = false
+	fl.split = false
+	return line
+}
+
+// reset forgets the position in the file.
+func (fl *follower) reset() {
+	fl.offset = 0
+	fl.number = 0
+	fl.partial = nil
+	fl.skipped = 0
+	fl.cut = false
+	fl.split = false
+	fl.eol = false
+	fl.seeked = false
+}
+
+// check compares the open file with whatever is currently at the path.
+// It reports EventRotated when the path now points to a different file and
+// EventTruncated when the same file became smaller than what was read.
+func (fl *follower) check() (EventKind, bool, error) {
+	info, err := os.Stat(fl.path)
+	if errors.Is(err, os.ErrNotExist) {
+		// Between the rename and the creation of the new file there is
+		// nothing at the path; keep reading the old one meanwhile.
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	if !os.SameFile(fl.info, info) {
+		return EventRotated, true, nil
+	}
+	if info.Size() < fl.offset+fl.skipped+int64(len(fl.partial)) {
+		return EventTruncated, true, nil
+	}
+	return 0, false, nil
+}
+
+// reopen switches to the file currently at the path.
+func (fl *follower) reopen() error {
+	old := *fl
+	if err := fl.open(); err != nil {
+		return err
+	}
+	return old.close()
+}
+
+// rewind restarts reading a truncated file from the beginning.
+func (fl *follower) rewind() error {
+	if _, err := fl.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	fl.r.Reset(fl.f)
+	fl.reset()
+	if len(fl.stores) > 0 {
+		fl.id, _ = checkpoint.Identify(fl.f)
+	}
+	return nil
+}
+
+// checkpointID returns the identity progress is recorded under. Files
+// still shorter than a full fingerprint are identified again as they grow,
+// so the saved fingerprint covers what was read.
+func (fl *follower) checkpointID() checkpoint.Identity {
+	if fl.id.FingerprintLen < checkpoint.FingerprintSize {
+		if id, err := checkpoint.Identify(fl.f); err == nil {
+			fl.id = id
+		}
+	}
+	return fl.id
+}
+
+// runeBoundary moves a cut at n back to the start of a UTF-8 sequence, so
+// a long line is not broken in the middle of a character. b must be longer
+// than n.
+func runeBoundary(b []byte, n int) int {
+	for i := n; i > 0 && i > n-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			return i
+		}
+	}
+	return n
+}
+
+func trimEOL(b []byte) []byte {
+	b = bytes.TrimSuffix(b, []byte("\n"))
+	return bytes.TrimSuffix(b, []byte("\r"))
+}
@@ -0,0 +1,190 @@
+package reader
+
+import (
+	"context"
+	"logagg/internal/checkpoint"
+	"logagg/internal/logline"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPoll = 10 * time.Millisecond
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatalf("failed to write to %s: %v", path, err)
+	}
+}
+
+func expectLine(t *testing.T, ch <-chan logline.Line, want string) {
+	t.Helper()
+	select {
+	case line := <-ch:
+		if line.Raw != want {
+			t.Errorf("expected line %q, got %q", want, line.Raw)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for %q", want)
+	}
+}
+
+func expectEvent(t *testing.T, events <-chan Event, want EventKind) {
+	t.Helper()
+	select {
+	case ev := <-events:
+		if ev.Kind != want {
+			t.Errorf("expected event %v, got %v", want, ev.Kind)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for event %v", want)
+	}
+}
+
+func TestRead_FollowsRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	appendFile(t, path, "before rotation\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 1)
+	ch := Read(ctx, path, Options{Tail: true, PollInterval: testPoll, Events: events})
+
+	expectLine(t, ch, "before rotation")
+
+	// Lines written right before the rename must still be delivered.
+	appendFile(t, path, "last old line\n")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+	appendFile(t, path, "after rotation\n")
+
+	expectLine(t, ch, "last old line")
+	expectLine(t, ch, "after rotation")
+	expectEvent(t, events, EventRotated)
+}
+
+func TestRead_FollowsTruncation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "a fairly long first line\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 1)
+	ch := Read(ctx, path, Options{Tail: true, PollInterval: testPoll, Events: events})
+
+	expectLine(t, ch, "a fairly long first line")
+
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	expectEvent(t, events, EventTruncated)
+
+	appendFile(t, path, "fresh\n")
+	expectLine(t, ch, "fresh")
+}
+
+func TestRead_TailWaitsForCompleteLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "half")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := Read(ctx, path, Options{Tail: true, PollInterval: testPoll})
+
+	time.Sleep(50 * time.Millisecond)
+	appendFile(t, path, " and half\n")
+
+	expectLine(t, ch, "half and half")
+}
+
+func TestRead_ResumesFromCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	statePath := filepath.Join(dir, "state.json")
+	appendFile(t, path, "one\ntwo\n")
+
+	read := func() []logline.Line {
+		store, err := checkpoint.Open(statePath)
+		if err != nil {
+			t.Fatalf("checkpoint.Open() unexpected error = %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		var lines []logline.Line
+		for l := range Read(ctx, path, Options{Checkpoints: store}) {
+			lines = append(lines, l)
+			// Written, as the output stage would.
+			l.Checkpoint.Commit()
+		}
+		if err := store.Flush(); err != nil {
+			t.Fatalf("Flush() unexpected error = %v", err)
+		}
+		return lines
+	}
+
+	if first := read(); len(first) != 2 {
+		t.Fatalf("expected 2 lines on first run, got %d", len(first))
+	}
+
+	appendFile(t, path, "three\n")
+
+	second := read()
+	if len(second) != 1 {
+		t.Fatalf("expected only the new line after restart, got %d", len(second))
+	}
+	if second[0].Raw != "three" || second[0].Number != 3 || second[0].Offset != 8 {
+		t.Errorf("expected line 3 at offset 8, got %q line %d offset %d", second[0].Raw, second[0].Number, second[0].Offset)
+	}
+}
+
+func TestRead_CheckpointSkipsOnlyWrittenLines(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	statePath := filepath.Join(dir, "state.json")
+	appendFile(t, path, "one\ntwo\nthree\n")
+
+	store, err := checkpoint.Open(statePath)
+	if err != nil {
+		t.Fatalf("checkpoint.Open() unexpected error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := Read(ctx, path, Options{Tail: true, PollInterval: testPoll, Checkpoints: store})
+
+	// "one" is written; "two" is still in flight when the run is cancelled.
+	(<-ch).Checkpoint.Commit()
+	<-ch
+	cancel()
+	for range ch {
+	}
+	if err := store.Flush(); err != nil {
+		t.Fatalf("Flush() unexpected error = %v", err)
+	}
+
+	store, err = checkpoint.Open(statePath)
+	if err != nil {
+		t.Fatalf("checkpoint.Open() unexpected error = %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	var got []string
+	for l := range Read(ctx, path, Options{Checkpoints: store}) {
+		got = append(got, l.Raw)
+	}
+	if strings.Join(got, ",") != "two,three" {
+		t.Errorf("expected the lines not written to be read again, got %q", got)
+	}
+}
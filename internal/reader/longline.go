@@ -0,0 +1,68 @@
+package reader
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// DefaultMaxLineSize is the longest line, in bytes, emitted whole when
+// LineLimit.Max is not set.
+const DefaultMaxLineSize = 1 << 20
+
+// TruncatedMarker is appended to lines cut by LongLineTruncate.
+const TruncatedMarker = "...[truncada]"
+
+// LongLinePolicy decides what happens to a line longer than the limit.
+type LongLinePolicy string
+
+const (
+	// LongLineTruncate keeps the first bytes of the line followed by
+	// TruncatedMarker and discards the rest. It is the default.
+	LongLineTruncate LongLinePolicy = "truncate"
+	// LongLineSplit emits the line in chunks of at most the limit, all
+	// with the same line number.
+	LongLineSplit LongLinePolicy = "split"
+	// LongLineSkip drops the line entirely.
+	LongLineSkip LongLinePolicy = "skip"
+)
+
+func ParseLongLinePolicy(s string) (LongLinePolicy, error) {
+	switch p := LongLinePolicy(s); p {
+	case LongLineTruncate, LongLineSplit, LongLineSkip:
+		return p, nil
+	default:
+		return "", fmt.Errorf("política de linhas longas inválida %q: use %q, %q ou %q", s, LongLineTruncate, LongLineSplit, LongLineSkip)
+	}
+}
+
+// LineLimit bounds the memory a single line may take. Lines are never
+// held in full beyond Max bytes, whatever the policy.
+type LineLimit struct {
+	// Max is the longest line in bytes, without its terminator; zero means
+	// DefaultMaxLineSize.
+	Max    int
+	Policy LongLinePolicy
+	// Count, when set, is incremented once for every line over Max. It may
+	// be shared by several readers.
+	Count *atomic.Int64
+}
+
+func (l LineLimit) max() int {
+	if l.Max <= 0 {
+		return DefaultMaxLineSize
+	}
+	return l.Max
+}
+
+func (l LineLimit) policy() LongLinePolicy {
+	if l.Policy == "" {
+		return LongLineTruncate
+	}
+	return l.Policy
+}
+
+func (l LineLimit) count() {
+	if l.Count != nil {
+		l.Count.Add(1)
+	}
+}
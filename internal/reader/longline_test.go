@@ -0,0 +1,139 @@
+package reader
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseLongLinePolicy(t *testing.T) {
+	for _, s := range []string{"truncate", "split", "skip"} {
+		if _, err := ParseLongLinePolicy(s); err != nil {
+			t.Errorf("ParseLongLinePolicy(%q) unexpected error = %v", s, err)
+		}
+	}
+	if _, err := ParseLongLinePolicy("wrap"); err == nil {
+		t.Error("ParseLongLinePolicy() with unknown policy error = nil, want error")
+	}
+}
+
+func TestRead_LongLines(t *testing.T) {
+	long := strings.Repeat("x", 10000)
+
+	tests := []struct {
+		name    string
+		policy  LongLinePolicy
+		content string
+		want    []string
+		numbers []int64
+		count   int64
+	}{
+		{
+			name:    "truncate",
+			policy:  LongLineTruncate,
+			content: "short\n" + long + "\nafter\n",
+			want:    []string{"short", strings.Repeat("x", 4096) + TruncatedMarker, "after"},
+			numbers: []int64{1, 2, 3},
+			count:   1,
+		},
+		{
+			name:    "split",
+			policy:  LongLineSplit,
+			content: "short\n" + long + "\nafter\n",
+			want:    []string{"short", strings.Repeat("x", 4096), strings.Repeat("x", 4096), strings.Repeat("x", 1808), "after"},
+			numbers: []int64{1, 2, 2, 2, 3},
+			count:   1,
+		},
+		{
+			name:    "skip",
+			policy:  LongLineSkip,
+			content: "short\n" + long + "\nafter\n" + long,
+			want:    []string{"short", "after"},
+			numbers: []int64{1, 3},
+			count:   2,
+		},
+		{
+			name:    "exact limit is kept",
+			policy:  LongLineSkip,
+			content: strings.Repeat("y", 4096) + "\r\n",
+			want:    []string{strings.Repeat("y", 4096)},
+			numbers: []int64{1},
+			count:   0,
+		},
+		{
+			name:    "unterminated last line",
+			policy:  LongLineTruncate,
+			content: long,
+			want:    []string{strings.Repeat("x", 4096) + TruncatedMarker},
+			numbers: []int64{1},
+			count:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			appendFile(t, path, tt.content)
+
+			var count atomic.Int64
+			opts := Options{Limit: LineLimit{Max: 4096, Policy: tt.policy, Count: &count}}
+
+			var got []string
+			var numbers []int64
+			var end int64
+			for l := range Read(context.Background(), path, opts) {
+				if l.Offset < end {
+					t.Errorf("offset %d of line %d goes back before %d", l.Offset, l.Number, end)
+				}
+				end = l.Offset
+				got = append(got, l.Raw)
+				numbers = append(numbers, l.Number)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d lines, got %d", len(tt.want), len(got))
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("line %d: expected %d bytes, got %d", i, len(tt.want[i]), len(got[i]))
+				}
+				if numbers[i] != tt.numbers[i] {
+					t.Errorf("line %d: expected number %d, got %d", i, tt.numbers[i], numbers[i])
+				}
+			}
+			if count.Load() != tt.count {
+				t.Errorf("expected %d long lines counted, got %d", tt.count, count.Load())
+			}
+		})
+	}
+}
+
+func TestRead_SplitKeepsCharactersWhole(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "aé\n")
+
+	opts := Options{Limit: LineLimit{Max: 2, Policy: LongLineSplit}}
+	var got []string
+	for l := range Read(context.Background(), path, opts) {
+		got = append(got, l.Raw)
+	}
+	if len(got) != 2 || got[0] != "a" || got[1] != "é" {
+		t.Errorf("expected [a é], got %q", got)
+	}
+}
+
+func TestRead_TruncatedLineResumesAtNextLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, strings.Repeat("x", 100)+"\nnext\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := Read(ctx, path, Options{Tail: true, PollInterval: testPoll, Limit: LineLimit{Max: 10}})
+	expectLine(t, ch, strings.Repeat("x", 10)+TruncatedMarker)
+	expectLine(t, ch, "next")
+	appendFile(t, path, "later\n")
+	expectLine(t, ch, "later")
+}
@@ -1,46 +1,221 @@
 package reader
 
 import (
-	"bufio"
 	"context"
-	"fmt"
-	"log"
-	"os"
-	"path/filepath"
+	"errors"
+	"io"
+	"logagg/internal/checkpoint"
+	"logagg/internal/logline"
 	"time"
 )
 
-func ReadLines(ctx context.Context, file string, tail bool) <-chan string {
-	out := make(chan string)
+// DefaultPollInterval is how long tail mode waits at the end of a file
+// before looking for new content.
+const DefaultPollInterval = 500 * time.Millisecond
+
+// Options controls how a source is read.
+type Options struct {
+	// Tail keeps the source open after reaching the end, following the path
+	// across rotation and truncation like `tail -F`.
+	Tail bool
+	// Watch selects how tail mode waits for changes; empty means WatchPoll.
+	Watch WatchMode
+	// PollInterval overrides DefaultPollInterval when positive.
+	PollInterval time.Duration
+	// Events, when set, receives rotation and truncation notices and the
+	// errors of the source. Without it errors are only visible as the
+	// channel closing early.
+	Events chan<- Event
+	// Checkpoints, when set, makes the reader resume from the position
+	// saved by a previous run. Lines then carry their position in it, for
+	// the output stage to commit once they are written.
+	Checkpoints *checkpoint.Store
+	// Resume, when set, records where the reader is as lines are sent and
+	// is looked up before Checkpoints. It lets a reader stopped and started
+	// again within one run, as on a configuration reload, carry on after
+	// the last line sent rather than the last one written, which the
+	// stopped reader's pipeline is still delivering.
+	Resume *checkpoint.Store
+	// Limit bounds the length of a line.
+	Limit LineLimit
+	// Since, together with TimeOf, lets the reader of a large uncompressed
+	// file skip the lines older than Since by searching the file for the
+	// first line at or after it. TimeOf returns the time of a line. Lines
+	// around the start point may still be older, so Since must be applied
+	// downstream as well. A position resumed from Checkpoints wins.
+	Since  time.Time
+	TimeOf func(line string) (time.Time, bool)
+	// Label, when set, is given to every line as its display name.
+	Label string
+}
+
+func ReadLines(ctx context.Context, file string, tail bool) <-chan logline.Line {
+	return Read(ctx, file, Options{Tail: tail})
+}
+
+func Read(ctx context.Context, file string, opts Options) <-chan logline.Line {
+	out := make(chan logline.Line)
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
 
 	go func() {
 		defer close(out)
-		f, err := os.Open(file)
+		// lastErr avoids repeating the same error at every poll while a
+		// followed file stays unavailable.
+		var lastErr string
+		report := func(op Op, err error) bool {
+			if err.Error() == lastErr {
+				return true
+			}
+			lastErr = err.Error()
+			return notify(ctx, opts.Events, Event{
+				Kind:   EventError,
+				Source: file,
+				Err:    &SourceError{Source: file, Op: op, Err: err},
+			})
+		}
+
+		fl, err := openFollower(file, opts.Limit, opts.Resume, opts.Checkpoints)
 		if err != nil {
-			log.Fatal(err)
+			report(OpOpen, err)
+			return
+		}
+		defer fl.close()
+
+		if !opts.Since.IsZero() && opts.TimeOf != nil && fl.compression == Uncompressed &&
+			fl.offset == 0 && fl.info.Size() >= seekThreshold {
+			// When the search fails the whole file is read, which only
+			// costs time.
+			fl.seekTime(opts.Since, opts.TimeOf)
+		}
+
+		// Compressed files are archives of rotated logs; they never grow, so
+		// they are read once even in tail mode.
+		follow := opts.Tail && fl.compression == Uncompressed
+
+		var w waiter
+		if follow {
+			// Subscribe before the first read so a write landing while the
+			// existing content is consumed still wakes the reader.
+			w = newWaiter(file, opts, interval)
+			defer w.close()
+		}
+
+		var cursor *checkpoint.Cursor
+		if opts.Checkpoints != nil {
+			cursor = opts.Checkpoints.Cursor()
+		}
+		send := func(raw rawLine) bool {
+			line := logline.Line{
+				Source:   file,
+				Label:    opts.Label,
+				Raw:      raw.text,
+				Offset:   raw.offset,
+				Number:   raw.number,
+				Ingested: time.Now(),
+			}
+			if fl.seeked {
+				line.Number = 0
+			}
+			var id checkpoint.Identity
+			if opts.Checkpoints != nil || opts.Resume != nil {
+				id = fl.checkpointID()
+			}
+			if cursor != nil {
+				line.Checkpoint = cursor.Next(id, file, raw.end, line.Number)
+			}
+			select {
+			case out <- line:
+				if opts.Resume != nil {
+					opts.Resume.Update(id, file, raw.end, line.Number)
+				}
+				return true
+			case <-ctx.Done():
+				return false
+			}
 		}
-		defer f.Close()
 
-		scanner := bufio.NewScanner(f)
+		// drain emits every line left in the current file, including an
+		// unterminated last one. It returns false when reading must stop.
+		drain := func() bool {
+			for {
+				line, err := fl.readLine()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					report(OpRead, err)
+					return false
+				}
+				if !send(line) {
+					return false
+				}
+			}
+			if line, ok := fl.flush(); ok {
+				return send(line)
+			}
+			return true
+		}
 
 		for {
-			for scanner.Scan() {
-				select {
-				case out <- fmt.Sprintf("[%s] - %s", filepath.Base(file), scanner.Text()):
-				case <-ctx.Done():
+			line, err := fl.readLine()
+			if err == nil {
+				if !send(line) {
 					return
 				}
+				continue
+			}
+			if !errors.Is(err, io.EOF) {
+				report(OpRead, err)
+				return
 			}
 
-			if !tail {
+			if !follow {
+				drain()
 				return
 			}
 
-			select {
-			case <-ctx.Done():
+			if w.wait(ctx) != nil {
+				return
+			}
+
+			kind, changed, err := fl.check()
+			if err != nil {
+				if !report(OpStat, err) {
+					return
+				}
+				continue
+			}
+			if !changed {
+				lastErr = ""
+				continue
+			}
+
+			switch kind {
+			case EventRotated:
+				if !drain() {
+					return
+				}
+				if err := fl.reopen(); err != nil {
+					if !report(OpReopen, err) {
+						return
+					}
+					continue
+				}
+			case EventTruncated:
+				if err := fl.rewind(); err != nil {
+					if !report(OpRewind, err) {
+						return
+					}
+					continue
+				}
+			}
+			lastErr = ""
+
+			if !notify(ctx, opts.Events, Event{Kind: kind, Source: file}) {
 				return
-			case <-time.After(500 * time.Millisecond):
-				scanner = bufio.NewScanner(f)
 			}
 		}
 	}()
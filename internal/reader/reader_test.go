@@ -30,7 +30,7 @@ func TestReadLines_MultipleLines(t *testing.T) {
 
 	var lines []string
 	for line := range ch {
-		lines = append(lines, line)
+		lines = append(lines, line.Raw)
 	}
 
 	if len(lines) != 3 {
@@ -49,7 +49,7 @@ func TestReadLines_MultipleLines(t *testing.T) {
 	}
 }
 
-func TestReadLines_CorrectPrefix(t *testing.T) {
+func TestReadLines_CorrectSource(t *testing.T) {
 	// Create a temporary file
 	tmpFile, err := os.CreateTemp("", "myapp-*.log")
 	if err != nil {
@@ -69,14 +69,16 @@ func TestReadLines_CorrectPrefix(t *testing.T) {
 	ch := ReadLines(ctx, tmpFile.Name(), false)
 
 	line := <-ch
-	expectedPrefix := "[" + filepath.Base(tmpFile.Name()) + "]"
 
-	if !strings.HasPrefix(line, expectedPrefix) {
-		t.Errorf("expected line to start with %q, got %q", expectedPrefix, line)
+	if line.Source != tmpFile.Name() {
+		t.Errorf("expected source %q, got %q", tmpFile.Name(), line.Source)
+	}
+	if line.Name() != filepath.Base(tmpFile.Name()) {
+		t.Errorf("expected name %q, got %q", filepath.Base(tmpFile.Name()), line.Name())
 	}
 
-	if !strings.Contains(line, "test line") {
-		t.Errorf("expected line to contain 'test line', got %q", line)
+	if line.Raw != "test line" {
+		t.Errorf("expected raw text 'test line', got %q", line.Raw)
 	}
 }
 
@@ -134,7 +136,7 @@ func TestReadLines_EmptyFile(t *testing.T) {
 
 	var lines []string
 	for line := range ch {
-		lines = append(lines, line)
+		lines = append(lines, line.Raw)
 	}
 
 	if len(lines) != 0 {
@@ -163,8 +165,8 @@ func TestReadLines_TailMode(t *testing.T) {
 
 	// Read initial line
 	line := <-ch
-	if !strings.Contains(line, "initial line") {
-		t.Errorf("expected 'initial line', got %q", line)
+	if !strings.Contains(line.Raw, "initial line") {
+		t.Errorf("expected 'initial line', got %q", line.Raw)
 	}
 
 	// In tail mode, the goroutine should still be running
@@ -208,7 +210,7 @@ func TestReadLines_SingleLine(t *testing.T) {
 
 	var lines []string
 	for line := range ch {
-		lines = append(lines, line)
+		lines = append(lines, line.Raw)
 	}
 
 	if len(lines) != 1 {
@@ -219,3 +221,49 @@ func TestReadLines_SingleLine(t *testing.T) {
 		t.Errorf("expected line to contain %q, got %q", content, lines[0])
 	}
 }
+
+func TestReadLines_PositionMetadata(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "test-*.log")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	content := "first\r\nsecond\nthird"
+	if _, err := tmpFile.WriteString(content); err != nil {
+		t.Fatalf("failed to write to temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	expected := []struct {
+		raw    string
+		offset int64
+		number int64
+	}{
+		{"first", 0, 1},
+		{"second", 7, 2},
+		{"third", 14, 3},
+	}
+
+	i := 0
+	for line := range ReadLines(ctx, tmpFile.Name(), false) {
+		if i >= len(expected) {
+			t.Fatalf("unexpected extra line %q", line.Raw)
+		}
+		exp := expected[i]
+		if line.Raw != exp.raw || line.Offset != exp.offset || line.Number != exp.number {
+			t.Errorf("line %d: expected %q at offset %d number %d, got %q at offset %d number %d",
+				i, exp.raw, exp.offset, exp.number, line.Raw, line.Offset, line.Number)
+		}
+		if line.Ingested.IsZero() {
+			t.Errorf("line %d: expected ingest time to be set", i)
+		}
+		i++
+	}
+	if i != len(expected) {
+		t.Errorf("expected %d lines, got %d", len(expected), i)
+	}
+}
@@ -0,0 +1,90 @@
+package reader
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"time"
+)
+
+// seekThreshold is the size from which a file is searched for
+// Options.Since instead of being read from the start.
+const seekThreshold = 1 << 20
+
+// probeSize bounds how much is read at each step of the search to find a
+// line with a timestamp.
+const probeSize = 64 << 10
+
+// seekTime moves to the start of a line shortly before the first one timed
+// at or after since, using a binary search over the file that assumes its
+// lines are in chronological order. Lines read from there on have unknown
+// numbers. If the search cannot decide, reading starts where it last knew
+// the lines were too old, possibly the start of the file.
+func (fl *follower) seekTime(since time.Time, timeOf func(string) (time.Time, bool)) error {
+	lo, hi := int64(0), fl.info.Size()
+	for hi-lo > probeSize {
+		mid := lo + (hi-lo)/2
+		t, ok, err := fl.probe(mid, timeOf)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		if t.Before(since) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	if lo == 0 {
+		return nil
+	}
+
+	buf := make([]byte, probeSize)
+	n, err := fl.f.ReadAt(buf, lo)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	i := bytes.IndexByte(buf[:n], '\n')
+	if i < 0 {
+		return nil
+	}
+	start := lo + int64(i) + 1
+
+	if _, err := fl.f.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	fl.r.Reset(fl.f)
+	fl.offset = start
+	fl.seeked = true
+	return nil
+}
+
+// probe returns the time of the first timestamped line starting after off.
+func (fl *follower) probe(off int64, timeOf func(string) (time.Time, bool)) (time.Time, bool, error) {
+	buf := make([]byte, probeSize)
+	n, err := fl.f.ReadAt(buf, off)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return time.Time{}, false, err
+	}
+	buf = buf[:n]
+
+	// The probe most likely lands in the middle of a line.
+	i := bytes.IndexByte(buf, '\n')
+	if i < 0 {
+		return time.Time{}, false, nil
+	}
+	buf = buf[i+1:]
+
+	for {
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			return time.Time{}, false, nil
+		}
+		if t, ok := timeOf(string(trimEOL(buf[:i+1]))); ok {
+			return t, true, nil
+		}
+		buf = buf[i+1:]
+	}
+}
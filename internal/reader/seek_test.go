@@ -0,0 +1,132 @@
+package reader
+
+import (
+	"context"
+	"fmt"
+	"logagg/internal/checkpoint"
+	"logagg/internal/logline"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var seekBase = time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+func seekTimeOf(line string) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, strings.SplitN(line, " ", 2)[0])
+	return t, err == nil
+}
+
+// writeTimedFile writes one line per second, padded to 100 bytes, with an
+// untimed continuation line after every tenth.
+func writeTimedFile(t *testing.T, lines int) string {
+	t.Helper()
+	var b strings.Builder
+	for i := 0; i < lines; i++ {
+		line := fmt.Sprintf("%s line %d ", seekBase.Add(time.Duration(i)*time.Second).Format(time.RFC3339), i)
+		b.WriteString(line + strings.Repeat("x", 99-len(line)) + "\n")
+		if i%10 == 0 {
+			b.WriteString("\tcontinuation\n")
+		}
+	}
+	path := filepath.Join(t.TempDir(), "big.log")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRead_SinceSeeksNearStart(t *testing.T) {
+	path := writeTimedFile(t, 30000)
+	since := seekBase.Add(20000 * time.Second)
+
+	ch := Read(context.Background(), path, Options{Since: since, TimeOf: seekTimeOf})
+
+	first := <-ch
+	ft, ok := seekTimeOf(first.Raw)
+	if !ok {
+		// The search may stop on a continuation line.
+		first = <-ch
+		ft, _ = seekTimeOf(first.Raw)
+	}
+	if !ft.Before(since) {
+		t.Errorf("expected reading to start before %v, got %v", since, ft)
+	}
+	if since.Sub(ft) > time.Duration(2*probeSize/100)*time.Second {
+		t.Errorf("expected reading to start close to %v, got %v", since, ft)
+	}
+	if first.Number != 0 {
+		t.Errorf("expected unknown line number after seeking, got %d", first.Number)
+	}
+
+	var last string
+	count := 1
+	for l := range ch {
+		last = l.Raw
+		count++
+	}
+	if !strings.HasPrefix(last, "\tcontinuation") && !strings.Contains(last, "line 29999 ") {
+		t.Errorf("expected to read until the end, last line %q", last)
+	}
+	if count > 15000 {
+		t.Errorf("expected the start of the file to be skipped, read %d lines", count)
+	}
+}
+
+func TestRead_SinceBeforeFileReadsEverything(t *testing.T) {
+	path := writeTimedFile(t, 12000)
+
+	ch := Read(context.Background(), path, Options{Since: seekBase.Add(-time.Hour), TimeOf: seekTimeOf})
+	first := <-ch
+	if !strings.Contains(first.Raw, "line 0 ") || first.Number != 1 {
+		t.Errorf("expected to start at the first line, got %d %q", first.Number, first.Raw)
+	}
+	for range ch {
+	}
+}
+
+func TestRead_SinceIgnoredForSmallFiles(t *testing.T) {
+	path := writeTimedFile(t, 100)
+
+	ch := Read(context.Background(), path, Options{Since: seekBase.Add(time.Hour), TimeOf: seekTimeOf})
+	first := <-ch
+	if first.Number != 1 {
+		t.Errorf("expected small files to be read from the start, got line %d", first.Number)
+	}
+	for range ch {
+	}
+}
+
+func TestRead_SeekedCheckpointKeepsLineNumbersUnknown(t *testing.T) {
+	path := writeTimedFile(t, 12000)
+	store := checkpoint.NewMemory()
+
+	ch := Read(context.Background(), path, Options{Since: seekBase.Add(10000 * time.Second), TimeOf: seekTimeOf, Checkpoints: store})
+	for l := range ch {
+		// Written, as the output stage would.
+		l.Checkpoint.Commit()
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, e, ok := store.Lookup(f)
+	f.Close()
+	if !ok || e.Line != 0 {
+		t.Fatalf("expected the checkpoint to keep the line number unknown, got %+v (found %v)", e, ok)
+	}
+
+	appendFile(t, path, "appended\n")
+
+	ch = Read(context.Background(), path, Options{Checkpoints: store})
+	var got []logline.Line
+	for l := range ch {
+		got = append(got, l)
+	}
+	if len(got) != 1 || got[0].Raw != "appended" || got[0].Number != 0 {
+		t.Errorf("expected only the appended line with an unknown number, got %+v", got)
+	}
+}
@@ -1,7 +1,9 @@
 package reader
 
 import (
+	"bufio"
 	"errors"
+	"fmt"
 	"os"
 )
 
@@ -11,9 +13,34 @@ func ValidateFile(filename string) error {
 	if os.IsNotExist(err) {
 		return errors.New("arquivo não encontrado " + filename)
 	}
+	if err != nil {
+		return err
+	}
 	if info.IsDir() {
 		return errors.New("caminho é um diretório, não um arquivo: " + filename)
 	}
 
-	return nil
+	return validateCompression(filename)
+}
+
+// validateCompression makes sure a compressed file has a readable header,
+// so a corrupt archive is reported up front instead of mid-read.
+func validateCompression(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	c := sniff(br)
+	if c == Uncompressed {
+		return nil
+	}
+
+	dec, err := decompress(c, br)
+	if err != nil {
+		return fmt.Errorf("arquivo compactado (%s) inválido %s: %w", c, filename, err)
+	}
+	return dec.Close()
 }
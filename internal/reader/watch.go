@@ -0,0 +1,232 @@
+package reader
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchMode selects how tail mode learns that a file has changed.
+type WatchMode string
+
+const (
+	// WatchPoll checks the file again after a fixed interval. It is the
+	// default when Options.Watch is empty.
+	WatchPoll WatchMode = "poll"
+	// WatchNotify sleeps until the operating system reports a write,
+	// rename or removal (inotify on Linux, kqueue on BSD and macOS). If the
+	// watch cannot be set up the reader falls back to polling.
+	WatchNotify WatchMode = "fsnotify"
+)
+
+func ParseWatchMode(s string) (WatchMode, error) {
+	switch m := WatchMode(s); m {
+	case WatchPoll, WatchNotify:
+		return m, nil
+	default:
+		return "", fmt.Errorf("modo de observação inválido %q: use %q ou %q", s, WatchNotify, WatchPoll)
+	}
+}
+
+// waiter blocks a tail reader until its file may have new content.
+type waiter interface {
+	wait(ctx context.Context) error
+	close()
+}
+
+// notifyFallback is how many poll intervals a notify waiter sleeps at most,
+// so a lost or filtered event delays a source instead of stalling it.
+const notifyFallback = 4
+
+func newWaiter(path string, opts Options, interval time.Duration) waiter {
+	if opts.Watch == WatchNotify {
+		if w, err := sharedHub().subscribe(path, notifyFallback*interval); err == nil {
+			return w
+		}
+	}
+	return pollWaiter{interval: interval}
+}
+
+type pollWaiter struct {
+	interval time.Duration
+}
+
+func (p pollWaiter) wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(p.interval):
+		return nil
+	}
+}
+
+func (pollWaiter) close() {}
+
+// notifyHub shares a single fsnotify watcher among every followed file, so
+// tailing hundreds of files does not exhaust the per-user inotify instance
+// limit. Directories are watched instead of the files themselves because a
+// watch on a file is lost as soon as it is renamed by logrotate.
+type notifyHub struct {
+	mu      sync.Mutex
+	watcher *fsnotify.Watcher
+	err     error
+	dirs    map[string]int
+	subs    map[string]map[*notifyWaiter]struct{}
+}
+
+var (
+	hubOnce sync.Once
+	hub     *notifyHub
+)
+
+func sharedHub() *notifyHub {
+	hubOnce.Do(func() {
+		hub = &notifyHub{
+			dirs: make(map[string]int),
+			subs: make(map[string]map[*notifyWaiter]struct{}),
+		}
+		hub.watcher, hub.err = fsnotify.NewWatcher()
+		if hub.err == nil {
+			go hub.run()
+		}
+	})
+	return hub
+}
+
+func (h *notifyHub) run() {
+	for {
+		select {
+		case ev, ok := <-h.watcher.Events:
+			if !ok {
+				return
+			}
+			h.mu.Lock()
+			for w := range h.subs[filepath.Clean(ev.Name)] {
+				w.wake()
+			}
+			h.mu.Unlock()
+		case _, ok := <-h.watcher.Errors:
+			if !ok {
+				return
+			}
+			// An error usually means the event queue overflowed; wake
+			// everyone so nothing stays asleep on a lost event.
+			h.mu.Lock()
+			for _, subs := range h.subs {
+				for w := range subs {
+					w.wake()
+				}
+			}
+			h.mu.Unlock()
+		}
+	}
+}
+
+// subscribe watches the directory of path and, when path is a symbolic
+// link, the directory of its target too, since writes to the target are
+// reported there only.
+func (h *notifyHub) subscribe(path string, fallback time.Duration) (*notifyWaiter, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{abs}
+	// A missing path has no target yet; the fallback timer covers it.
+	if target, err := filepath.EvalSymlinks(abs); err == nil && target != abs {
+		paths = append(paths, target)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	w := &notifyWaiter{hub: h, fallback: fallback, c: make(chan struct{}, 1)}
+	for _, p := range paths {
+		dir := filepath.Dir(p)
+		if h.dirs[dir] == 0 {
+			if err := h.watcher.Add(dir); err != nil {
+				h.release(w)
+				return nil, err
+			}
+		}
+		h.dirs[dir]++
+		w.dirs = append(w.dirs, dir)
+
+		if h.subs[p] == nil {
+			h.subs[p] = make(map[*notifyWaiter]struct{})
+		}
+		h.subs[p][w] = struct{}{}
+		w.paths = append(w.paths, p)
+	}
+	return w, nil
+}
+
+func (h *notifyHub) unsubscribe(w *notifyWaiter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.release(w)
+}
+
+// release drops the watches of w. The caller holds h.mu.
+func (h *notifyHub) release(w *notifyWaiter) {
+	for _, p := range w.paths {
+		delete(h.subs[p], w)
+		if len(h.subs[p]) == 0 {
+			delete(h.subs, p)
+		}
+	}
+	for _, dir := range w.dirs {
+		h.dirs[dir]--
+		if h.dirs[dir] == 0 {
+			delete(h.dirs, dir)
+			h.watcher.Remove(dir)
+		}
+	}
+}
+
+type notifyWaiter struct {
+	hub *notifyHub
+	// paths are the followed path and, for a symbolic link, its target;
+	// dirs are the directories watched for them.
+	paths []string
+	dirs  []string
+	// fallback bounds each wait when positive.
+	fallback time.Duration
+	c        chan struct{}
+}
+
+// wake records that the file changed. A pending wake-up is enough, so
+// extra events are dropped instead of blocking the hub.
+func (w *notifyWaiter) wake() {
+	select {
+	case w.c <- struct{}{}:
+	default:
+	}
+}
+
+func (w *notifyWaiter) wait(ctx context.Context) error {
+	var timeout <-chan time.Time
+	if w.fallback > 0 {
+		t := time.NewTimer(w.fallback)
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-w.c:
+		return nil
+	case <-timeout:
+		return nil
+	}
+}
+
+func (w *notifyWaiter) close() {
+	w.hub.unsubscribe(w)
+}
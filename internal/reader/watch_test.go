@@ -0,0 +1,137 @@
+package reader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseWatchMode(t *testing.T) {
+	for _, s := range []string{"poll", "fsnotify"} {
+		if _, err := ParseWatchMode(s); err != nil {
+			t.Errorf("ParseWatchMode(%q) unexpected error = %v", s, err)
+		}
+	}
+	if _, err := ParseWatchMode("inotifyx"); err == nil {
+		t.Error("ParseWatchMode() with unknown mode error = nil, want error")
+	}
+}
+
+func TestRead_NotifyPicksUpNewLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "first\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// A huge poll interval makes sure the line only arrives through a
+	// filesystem notification.
+	ch := Read(ctx, path, Options{Tail: true, Watch: WatchNotify, PollInterval: time.Hour})
+
+	expectLine(t, ch, "first")
+	appendFile(t, path, "second\n")
+	expectLine(t, ch, "second")
+}
+
+func TestRead_NotifyFollowsRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "old\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 1)
+	ch := Read(ctx, path, Options{Tail: true, Watch: WatchNotify, PollInterval: time.Hour, Events: events})
+
+	expectLine(t, ch, "old")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+	appendFile(t, path, "new\n")
+
+	expectLine(t, ch, "new")
+	expectEvent(t, events, EventRotated)
+}
+
+func TestNotifyHub_SharesDirectoryWatch(t *testing.T) {
+	dir := t.TempDir()
+	h := sharedHub()
+	if h.err != nil {
+		t.Skipf("fsnotify unavailable: %v", h.err)
+	}
+
+	a, err := h.subscribe(filepath.Join(dir, "a.log"), 0)
+	if err != nil {
+		t.Fatalf("subscribe() error = %v", err)
+	}
+	b, err := h.subscribe(filepath.Join(dir, "b.log"), 0)
+	if err != nil {
+		t.Fatalf("subscribe() error = %v", err)
+	}
+
+	h.mu.Lock()
+	refs := h.dirs[a.dirs[0]]
+	h.mu.Unlock()
+	if refs != 2 {
+		t.Errorf("expected 2 references to %s, got %d", a.dirs[0], refs)
+	}
+
+	a.close()
+	b.close()
+
+	h.mu.Lock()
+	_, watched := h.dirs[a.dirs[0]]
+	h.mu.Unlock()
+	if watched {
+		t.Errorf("expected %s to be unwatched after all subscribers closed", a.dirs[0])
+	}
+}
+
+func TestRead_NotifyFollowsSymlinkTarget(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "data", "app.log")
+	link := filepath.Join(dir, "logs", "app.log")
+	for _, d := range []string{filepath.Dir(target), filepath.Dir(link)} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	appendFile(t, target, "first\n")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := Read(ctx, link, Options{Tail: true, Watch: WatchNotify, PollInterval: time.Hour})
+
+	expectLine(t, ch, "first")
+	appendFile(t, target, "second\n")
+	expectLine(t, ch, "second")
+}
+
+func TestNotifyWaiter_WakesWithoutEvents(t *testing.T) {
+	h := sharedHub()
+	if h.err != nil {
+		t.Skipf("fsnotify unavailable: %v", h.err)
+	}
+	w, err := h.subscribe(filepath.Join(t.TempDir(), "app.log"), 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("subscribe() error = %v", err)
+	}
+	defer w.close()
+
+	done := make(chan error, 1)
+	go func() { done <- w.wait(context.Background()) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("wait() unexpected error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected wait() to return once the fallback elapsed")
+	}
+}
@@ -0,0 +1,349 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Spec describes where log files come from: a plain path, a glob pattern
+// (where ** matches any number of directories) or a directory, which is
+// searched recursively.
+type Spec struct {
+	// Name identifies the configuration file source the spec comes from;
+	// it is empty for sources given on the command line.
+	Name    string
+	Pattern string
+	// Include, when not empty, keeps only files whose base name matches one
+	// of these globs. It applies to files found through globs and
+	// directories, never to files named explicitly.
+	Include []string
+	// Exclude drops files whose base name matches one of these globs.
+	Exclude []string
+}
+
+func hasMeta(s string) bool {
+	return strings.ContainsAny(s, `*?[\`)
+}
+
+// Expand lists the files currently matching the spec, sorted by path. A
+// plain path that does not exist is returned unchanged so the caller can
+// report it.
+func (s Spec) Expand() ([]string, error) {
+	if !hasMeta(s.Pattern) {
+		info, err := os.Stat(s.Pattern)
+		if err != nil || !info.IsDir() {
+			return []string{s.Pattern}, nil
+		}
+		return s.walk(s.Pattern, func(string) bool { return true })
+	}
+
+	if !strings.Contains(s.Pattern, "**") {
+		matches, err := filepath.Glob(s.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		var files []string
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && info.Mode().IsRegular() && s.keep(m) {
+				files = append(files, m)
+			}
+		}
+		return files, nil
+	}
+
+	pattern := filepath.Clean(s.Pattern)
+	return s.walk(root(pattern), func(path string) bool {
+		return Match(pattern, path)
+	})
+}
+
+func (s Spec) walk(dir string, match func(string) bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable subdirectories are skipped rather than failing
+			// the whole source.
+			if d != nil && d.IsDir() && path != dir {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if d.Type().IsRegular() && match(path) && s.keep(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	sort.Strings(files)
+	return files, err
+}
+
+func (s Spec) keep(path string) bool {
+	name := filepath.Base(path)
+	for _, p := range s.Exclude {
+		if ok, _ := filepath.Match(p, name); ok {
+			return false
+		}
+	}
+	if len(s.Include) == 0 {
+		return true
+	}
+	for _, p := range s.Include {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// root returns the longest leading directory of pattern without glob
+// metacharacters, where a walk has to start.
+func root(pattern string) string {
+	parts := strings.Split(pattern, string(filepath.Separator))
+	i := 0
+	for i < len(parts)-1 && !hasMeta(parts[i]) {
+		i++
+	}
+	dir := strings.Join(parts[:i], string(filepath.Separator))
+	switch {
+	case dir == "" && filepath.IsAbs(pattern):
+		return string(filepath.Separator)
+	case dir == "":
+		return "."
+	}
+	return dir
+}
+
+// Match reports whether path matches pattern, where each path segment is
+// matched with filepath.Match and a ** segment matches zero or more
+// segments.
+func Match(pattern, path string) bool {
+	sep := string(filepath.Separator)
+	return matchParts(strings.Split(filepath.Clean(pattern), sep), strings.Split(filepath.Clean(path), sep))
+}
+
+func matchParts(pattern, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(path); i++ {
+				if matchParts(pattern[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 {
+			return false
+		}
+		if ok, _ := filepath.Match(pattern[0], path[0]); !ok {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
+
+// Discoverer finds the files of a set of specs and, over time, the files
+// that start matching them later.
+type Discoverer struct {
+	// Skipped, when set, is called with each file Scan leaves out as a
+	// rotated copy and the path it is a copy of.
+	Skipped func(path, of string)
+
+	specs []Spec
+
+	mu   sync.Mutex
+	seen map[string]*seenFile
+}
+
+// seenFile is a path returned by Scan, or skipped as a rotated copy of one.
+type seenFile struct {
+	spec Spec
+	// info identifies the file found at the path by the last scan; it is
+	// nil once the path is gone.
+	info os.FileInfo
+	// rotated is set once the path was seen replaced, removed or
+	// truncated, as logrotate does.
+	rotated bool
+}
+
+func NewDiscoverer(specs []Spec) *Discoverer {
+	return &Discoverer{specs: specs, seen: make(map[string]*seenFile)}
+}
+
+func key(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// Origin returns the spec through which a file returned by Scan or Watch was
+// first found.
+func (d *Discoverer) Origin(path string) (Spec, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	f, ok := d.seen[key(path)]
+	if !ok {
+		return Spec{}, false
+	}
+	return f.spec, true
+}
+
+// Add starts looking for the files of spec, replacing the spec with the
+// same name, if any, as Remove does.
+func (d *Discoverer) Add(spec Spec) {
+	d.Remove(spec.Name)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.specs = append(d.specs, spec)
+}
+
+// Remove stops looking for the files of the specs named name. Files found
+// through them are forgotten, so a spec added later reports them again.
+func (d *Discoverer) Remove(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	specs := d.specs[:0:0]
+	for _, s := range d.specs {
+		if s.Name != name {
+			specs = append(specs, s)
+		}
+	}
+	d.specs = specs
+	for k, f := range d.seen {
+		if f.spec.Name == name {
+			delete(d.seen, k)
+		}
+	}
+}
+
+// Scan returns the files matching any spec that were not returned by a
+// previous call. Rotated copies of files found before are left out, since
+// the reader following the original path already emitted their content:
+// a file renamed from a path seen before, recognised by device and inode,
+// and, once that path has been rotated, a file named like a rotation of
+// it, such as app.log.1, app.log.2.gz or app.log-20240115.gz for app.log,
+// which catches copies and compressed archives. Files named like that
+// next to a path that was never rotated, such as worker-2 next to worker,
+// are returned.
+func (d *Discoverer) Scan() ([]string, error) {
+	d.mu.Lock()
+	specs := d.specs
+	d.mu.Unlock()
+
+	type match struct {
+		path string
+		spec Spec
+	}
+	var matches []match
+	var errs []error
+	current := make(map[string]os.FileInfo)
+	for _, s := range specs {
+		files, err := s.Expand()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, f := range files {
+			matches = append(matches, match{f, s})
+			if info, err := os.Stat(f); err == nil {
+				current[key(f)] = info
+			}
+		}
+	}
+
+	d.mu.Lock()
+	before := make(map[string]*seenFile, len(d.seen))
+	for k, f := range d.seen {
+		if cur := current[k]; f.info != nil && (cur == nil || !os.SameFile(f.info, cur) || cur.Size() < f.info.Size()) {
+			f.rotated = true
+		}
+		before[k] = f
+	}
+	var found []string
+	var skipped [][2]string
+	for _, m := range matches {
+		k := key(m.path)
+		if _, ok := d.seen[k]; ok {
+			continue
+		}
+		d.seen[k] = &seenFile{spec: m.spec}
+		if of, ok := rotated(k, current[k], before); ok {
+			skipped = append(skipped, [2]string{m.path, of})
+			continue
+		}
+		found = append(found, m.path)
+	}
+	for k, f := range d.seen {
+		f.info = current[k]
+	}
+	d.mu.Unlock()
+
+	if d.Skipped != nil {
+		for _, s := range skipped {
+			d.Skipped(s[0], s[1])
+		}
+	}
+	return found, errors.Join(errs...)
+}
+
+// rotationSuffix is what logrotate appends to the name of a rotated file,
+// numbered or dated, and compressed or not.
+var rotationSuffix = regexp.MustCompile(`^[.-]\d+(\.(gz|bz2|zst|xz))?$`)
+
+// rotated reports whether the file at path, found for the first time, is a
+// rotated copy of one of the files seen before, and of which.
+func rotated(path string, info os.FileInfo, seen map[string]*seenFile) (string, bool) {
+	dir, name := filepath.Split(path)
+	for k, f := range seen {
+		if info != nil && f.info != nil && os.SameFile(info, f.info) {
+			return k, true
+		}
+		d, base := filepath.Split(k)
+		if f.rotated && d == dir && strings.HasPrefix(name, base) && rotationSuffix.MatchString(name[len(base):]) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// Watch rescans the specs every interval and sends each newly appearing
+// file. The channel is closed when ctx is done.
+func (d *Discoverer) Watch(ctx context.Context, interval time.Duration) <-chan string {
+	out := make(chan string)
+
+	go func() {
+		defer close(out)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			files, _ := d.Scan()
+			for _, f := range files {
+				select {
+				case out <- f:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,285 @@
+package source
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// tree creates the given files under a temporary directory and returns it.
+func tree(t *testing.T, files ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, f := range files {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("x\n"), 0o644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+	}
+	return dir
+}
+
+func rel(t *testing.T, dir string, paths []string) []string {
+	t.Helper()
+	out := make([]string, 0, len(paths))
+	for _, p := range paths {
+		r, err := filepath.Rel(dir, p)
+		if err != nil {
+			t.Fatalf("failed to make %s relative: %v", p, err)
+		}
+		out = append(out, filepath.ToSlash(r))
+	}
+	return out
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/var/log/*.log", "/var/log/app.log", true},
+		{"/var/log/*.log", "/var/log/app/app.log", false},
+		{"/var/log/**/*.log", "/var/log/app.log", true},
+		{"/var/log/**/*.log", "/var/log/app/worker-1/app.log", true},
+		{"/var/log/**/*.log", "/var/log/app/app.txt", false},
+		{"**/*.log", "a/b/c.log", true},
+		{"/var/log/**", "/var/log/a/b", true},
+		{"/var/**/app/*.log", "/var/log/app/x.log", true},
+		{"/var/**/app/*.log", "/var/log/web/x.log", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			if got := Match(tt.pattern, tt.path); got != tt.want {
+				t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpec_Expand(t *testing.T) {
+	dir := tree(t,
+		"app.log",
+		"app.log.1.gz",
+		"db.log",
+		"notes.txt",
+		"workers/w1.log",
+		"workers/w2.log",
+		"workers/deep/w3.log",
+	)
+
+	tests := []struct {
+		name string
+		spec Spec
+		want []string
+	}{
+		{
+			name: "plain file",
+			spec: Spec{Pattern: filepath.Join(dir, "app.log")},
+			want: []string{"app.log"},
+		},
+		{
+			name: "glob",
+			spec: Spec{Pattern: filepath.Join(dir, "*.log")},
+			want: []string{"app.log", "db.log"},
+		},
+		{
+			name: "recursive glob",
+			spec: Spec{Pattern: filepath.Join(dir, "**", "*.log")},
+			want: []string{"app.log", "db.log", "workers/deep/w3.log", "workers/w1.log", "workers/w2.log"},
+		},
+		{
+			name: "directory",
+			spec: Spec{Pattern: filepath.Join(dir, "workers")},
+			want: []string{"workers/deep/w3.log", "workers/w1.log", "workers/w2.log"},
+		},
+		{
+			name: "directory with include and exclude",
+			spec: Spec{Pattern: dir, Include: []string{"*.log", "*.gz"}, Exclude: []string{"w*"}},
+			want: []string{"app.log", "app.log.1.gz", "db.log"},
+		},
+		{
+			name: "glob with exclude",
+			spec: Spec{Pattern: filepath.Join(dir, "app.log*"), Exclude: []string{"*.gz"}},
+			want: []string{"app.log"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.spec.Expand()
+			if err != nil {
+				t.Fatalf("Expand() unexpected error = %v", err)
+			}
+			if r := rel(t, dir, got); !reflect.DeepEqual(r, tt.want) {
+				t.Errorf("Expand() = %v, want %v", r, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpec_ExpandMissingFile(t *testing.T) {
+	got, err := Spec{Pattern: "/tmp/nonexistent-file-12345.log"}.Expand()
+	if err != nil {
+		t.Fatalf("Expand() unexpected error = %v", err)
+	}
+	if len(got) != 1 || got[0] != "/tmp/nonexistent-file-12345.log" {
+		t.Errorf("expected the missing path to be returned as is, got %v", got)
+	}
+}
+
+func TestDiscoverer_ScanReturnsOnlyNewFiles(t *testing.T) {
+	dir := tree(t, "a.log")
+	d := NewDiscoverer([]Spec{
+		{Pattern: filepath.Join(dir, "*.log")},
+		{Pattern: filepath.Join(dir, "a.log")},
+	})
+
+	first, err := d.Scan()
+	if err != nil {
+		t.Fatalf("Scan() unexpected error = %v", err)
+	}
+	if r := rel(t, dir, first); !reflect.DeepEqual(r, []string{"a.log"}) {
+		t.Errorf("first Scan() = %v, want [a.log]", r)
+	}
+
+	os.WriteFile(filepath.Join(dir, "b.log"), nil, 0o644)
+
+	second, _ := d.Scan()
+	if r := rel(t, dir, second); !reflect.DeepEqual(r, []string{"b.log"}) {
+		t.Errorf("second Scan() = %v, want [b.log]", r)
+	}
+}
+
+func TestDiscoverer_ScanSkipsRotatedFiles(t *testing.T) {
+	dir := tree(t, "app.log", "db.log", "old/archive.log.1")
+	d := NewDiscoverer([]Spec{{Pattern: dir}})
+	if r, _ := d.Scan(); !reflect.DeepEqual(rel(t, dir, r), []string{"app.log", "db.log", "old/archive.log.1"}) {
+		t.Fatalf("first Scan() = %v", rel(t, dir, r))
+	}
+
+	path := func(name string) string { return filepath.Join(dir, name) }
+	// logrotate renames app.log and creates a new one, compresses an
+	// older rotation, and db.log is moved away under another name.
+	os.Rename(path("app.log"), path("app.log.1"))
+	os.WriteFile(path("app.log"), nil, 0o644)
+	os.WriteFile(path("app.log.2.gz"), []byte{0x1f, 0x8b}, 0o644)
+	os.Rename(path("db.log"), path("db.log.old"))
+	os.WriteFile(path("worker.log"), nil, 0o644)
+
+	if r, _ := d.Scan(); !reflect.DeepEqual(rel(t, dir, r), []string{"worker.log"}) {
+		t.Errorf("Scan() after rotation = %v, want [worker.log]", rel(t, dir, r))
+	}
+
+	// Rotated again, app.log.1 keeps being recognised.
+	os.Rename(path("app.log.1"), path("app.log-20240115"))
+	if r, _ := d.Scan(); len(r) != 0 {
+		t.Errorf("Scan() after a second rotation = %v, want none", rel(t, dir, r))
+	}
+}
+
+func TestDiscoverer_ScanKeepsFilesNamedLikeWorkers(t *testing.T) {
+	dir := tree(t, "w/worker", "w/app.log")
+	d := NewDiscoverer([]Spec{{Pattern: filepath.Join(dir, "w")}})
+	var skipped []string
+	d.Skipped = func(path, of string) {
+		skipped = append(skipped, filepath.Base(path)+" of "+filepath.Base(of))
+	}
+	d.Scan()
+
+	path := func(name string) string { return filepath.Join(dir, "w", name) }
+	// New processes write files of their own next to an untouched one.
+	os.WriteFile(path("worker-2"), nil, 0o644)
+	os.WriteFile(path("worker.3"), nil, 0o644)
+	if r, _ := d.Scan(); !reflect.DeepEqual(rel(t, dir, r), []string{"w/worker-2", "w/worker.3"}) {
+		t.Errorf("Scan() = %v, want [w/worker-2 w/worker.3]", rel(t, dir, r))
+	}
+
+	// Once app.log is rotated, a compressed copy of it is skipped.
+	os.Rename(path("app.log"), filepath.Join(dir, "moved"))
+	os.WriteFile(path("app.log"), nil, 0o644)
+	d.Scan()
+	os.WriteFile(path("app.log.1.gz"), []byte{0x1f, 0x8b}, 0o644)
+	if r, _ := d.Scan(); len(r) != 0 {
+		t.Errorf("Scan() after rotation = %v, want none", rel(t, dir, r))
+	}
+	if want := []string{"app.log.1.gz of app.log"}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("Skipped got %v, want %v", skipped, want)
+	}
+}
+
+func TestDiscoverer_AddRemove(t *testing.T) {
+	dir := tree(t, "api.log", "worker.log")
+	d := NewDiscoverer(nil)
+
+	d.Add(Spec{Name: "api", Pattern: filepath.Join(dir, "api.log")})
+	if r, _ := d.Scan(); !reflect.DeepEqual(rel(t, dir, r), []string{"api.log"}) {
+		t.Errorf("Scan() after Add = %v, want [api.log]", rel(t, dir, r))
+	}
+
+	// Replacing a spec forgets its files, so they are reported again.
+	d.Add(Spec{Name: "api", Pattern: filepath.Join(dir, "*.log")})
+	if r, _ := d.Scan(); !reflect.DeepEqual(rel(t, dir, r), []string{"api.log", "worker.log"}) {
+		t.Errorf("Scan() after replacing = %v, want [api.log worker.log]", rel(t, dir, r))
+	}
+
+	d.Remove("api")
+	if _, ok := d.Origin(filepath.Join(dir, "api.log")); ok {
+		t.Error("Origin() ok = true after Remove")
+	}
+	if r, _ := d.Scan(); len(r) != 0 {
+		t.Errorf("Scan() after Remove = %v, want none", r)
+	}
+}
+
+func TestDiscoverer_Origin(t *testing.T) {
+	dir := tree(t, "api.log", "worker.log")
+	d := NewDiscoverer([]Spec{
+		{Name: "api", Pattern: filepath.Join(dir, "api.log")},
+		{Name: "all", Pattern: dir},
+	})
+	d.Scan()
+
+	tests := map[string]string{"api.log": "api", "worker.log": "all"}
+	for file, want := range tests {
+		s, ok := d.Origin(filepath.Join(dir, file))
+		if !ok || s.Name != want {
+			t.Errorf("Origin(%s) = %q, %v; want %q", file, s.Name, ok, want)
+		}
+	}
+	if _, ok := d.Origin(filepath.Join(dir, "missing.log")); ok {
+		t.Error("Origin() ok = true for a file never found")
+	}
+}
+
+func TestDiscoverer_WatchPicksUpNewFiles(t *testing.T) {
+	dir := tree(t, "worker-1.log")
+	d := NewDiscoverer([]Spec{{Pattern: dir}})
+	d.Scan()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	found := d.Watch(ctx, 10*time.Millisecond)
+	os.WriteFile(filepath.Join(dir, "worker-2.log"), nil, 0o644)
+
+	select {
+	case f := <-found:
+		if filepath.Base(f) != "worker-2.log" {
+			t.Errorf("expected worker-2.log, got %s", f)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("new file was not discovered")
+	}
+
+	cancel()
+	for range found {
+	}
+}
@@ -0,0 +1,73 @@
+package timestamp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// boundLayouts are the absolute times accepted by ParseBound, read in
+// local time unless they carry a zone.
+var boundLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// clockLayouts are times of day, taken as today.
+var clockLayouts = []string{"15:04:05", "15:04"}
+
+// ParseBound reads the limit of a time range: either an absolute time such
+// as "2024-01-15 10:00" or "10:00" (today), or a duration before now such as
+// "15m", "2h30m" or "7d".
+func ParseBound(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if d, ok := parseAgo(s); ok {
+		return now.Add(-d), nil
+	}
+	for _, layout := range boundLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range clockLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			y, m, d := now.Date()
+			return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), 0, now.Location()), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("tempo inválido %q: use uma duração como 15m, 2h ou 7d, ou uma data como \"2024-01-15 10:00\"", s)
+}
+
+// parseAgo extends time.ParseDuration with days (d) and weeks (w).
+func parseAgo(s string) (time.Duration, bool) {
+	var total time.Duration
+	rest := s
+	for _, unit := range []struct {
+		suffix string
+		size   time.Duration
+	}{{"w", 7 * 24 * time.Hour}, {"d", 24 * time.Hour}} {
+		i := strings.Index(rest, unit.suffix)
+		if i <= 0 {
+			continue
+		}
+		n, err := strconv.Atoi(rest[:i])
+		if err != nil {
+			return 0, false
+		}
+		total += time.Duration(n) * unit.size
+		rest = rest[i+1:]
+	}
+	if rest == "" {
+		return total, rest != s
+	}
+	d, err := time.ParseDuration(rest)
+	if err != nil || d < 0 {
+		return 0, false
+	}
+	return total + d, true
+}
@@ -0,0 +1,45 @@
+package timestamp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBound(t *testing.T) {
+	loc := time.FixedZone("BRT", -3*3600)
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, loc)
+
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"15m", now.Add(-15 * time.Minute)},
+		{"2h30m", now.Add(-150 * time.Minute)},
+		{"7d", now.Add(-7 * 24 * time.Hour)},
+		{"1w2d", now.Add(-9 * 24 * time.Hour)},
+		{"1d12h", now.Add(-36 * time.Hour)},
+		{"2024-01-15 10:00", time.Date(2024, 1, 15, 10, 0, 0, 0, loc)},
+		{"2024-01-15 10:00:30", time.Date(2024, 1, 15, 10, 0, 30, 0, loc)},
+		{"2024-01-14", time.Date(2024, 1, 14, 0, 0, 0, 0, loc)},
+		{"2024-01-15T10:00:00Z", time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)},
+		{"09:30", time.Date(2024, 1, 15, 9, 30, 0, 0, loc)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseBound(tt.in, now)
+			if err != nil {
+				t.Fatalf("ParseBound() unexpected error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseBound() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, bad := range []string{"", "yesterday", "-5m", "d", "2024-13-01"} {
+		if _, err := ParseBound(bad, now); err == nil {
+			t.Errorf("ParseBound(%q) error = nil, want error", bad)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package timestamp
+
+import (
+	"context"
+	"fmt"
+	"logagg/internal/logline"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layout is a way timestamps are written in log lines. It knows how to find
+// one in a line and how to read it.
+type Layout struct {
+	Name string
+	re   *regexp.Regexp
+	// parse reads the matched text. loc applies to times written without a
+	// zone, and ref is the time the line was read, which supplies the year
+	// to layouts without one.
+	parse func(s string, loc *time.Location, ref time.Time) (time.Time, bool)
+}
+
+// find returns the text of the first timestamp of this layout in line, or
+// of its first group when the expression has one.
+func (l *Layout) find(line string) (string, bool) {
+	m := l.re.FindStringSubmatch(line)
+	if m == nil {
+		return "", false
+	}
+	return m[len(m)-1], true
+}
+
+// Parse finds and reads a timestamp of this layout in line.
+func (l *Layout) Parse(line string, loc *time.Location, ref time.Time) (time.Time, bool) {
+	s, ok := l.find(line)
+	if !ok {
+		return time.Time{}, false
+	}
+	return l.parse(s, loc, ref)
+}
+
+// goLayouts returns a parse function trying Go layouts in order.
+func goLayouts(layouts ...string) func(string, *time.Location, time.Time) (time.Time, bool) {
+	return func(s string, loc *time.Location, _ time.Time) (time.Time, bool) {
+		for _, layout := range layouts {
+			if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+				return t, true
+			}
+		}
+		return time.Time{}, false
+	}
+}
+
+func epochParse(s string, _ *time.Location, _ time.Time) (time.Time, bool) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return Epoch(f), true
+}
+
+// Layouts are the layouts the Detector knows, in the order they are tried.
+// Fractional seconds, with a dot or a comma, are accepted wherever seconds
+// are. Epoch and syslog timestamps are only looked for at the start of a
+// line, where a number or a month name is unlikely to mean anything else.
+var Layouts = []*Layout{
+	{
+		Name:  "rfc3339",
+		re:    regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?`),
+		parse: goLayouts("2006-01-02T15:04:05Z07:00", "2006-01-02T15:04:05Z0700", "2006-01-02T15:04:05"),
+	},
+	{
+		Name:  "datetime",
+		re:    regexp.MustCompile(`\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?: ?(?:Z|[+-]\d{2}:?\d{2})\b)?`),
+		parse: goLayouts("2006-01-02 15:04:05Z07:00", "2006-01-02 15:04:05 Z07:00", "2006-01-02 15:04:05Z0700", "2006-01-02 15:04:05 -0700", "2006-01-02 15:04:05"),
+	},
+	{
+		Name:  "slash",
+		re:    regexp.MustCompile(`\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}(?:[.,]\d+)?`),
+		parse: goLayouts("2006/01/02 15:04:05"),
+	},
+	{
+		Name:  "apache",
+		re:    regexp.MustCompile(`\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}`),
+		parse: goLayouts("02/Jan/2006:15:04:05 -0700"),
+	},
+	{
+		Name: "syslog",
+		re:   regexp.MustCompile(`^(?:<\d{1,3}>)?([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}(?:\.\d+)?)`),
+		parse: func(s string, loc *time.Location, ref time.Time) (time.Time, bool) {
+			t, err := time.ParseInLocation(time.Stamp, s, loc)
+			if err != nil {
+				return time.Time{}, false
+			}
+			return InferYear(t, ref), true
+		},
+	},
+	{
+		Name:  "epoch_ms",
+		re:    regexp.MustCompile(`^\s*\[?(\d{13})\b`),
+		parse: epochParse,
+	},
+	{
+		Name:  "epoch",
+		re:    regexp.MustCompile(`^\s*\[?(\d{10}(?:\.\d+)?)\b`),
+		parse: epochParse,
+	},
+}
+
+// ParseLayout returns the named layout, or builds one from a Go reference
+// layout such as "02.01.2006 15:04:05" for formats the detector does not
+// know. A layout without a year takes it from the time the line was read.
+func ParseLayout(s string) (*Layout, error) {
+	for _, l := range Layouts {
+		if l.Name == s {
+			return l, nil
+		}
+	}
+
+	pattern, hasYear, ok := layoutRegexp(s)
+	if !ok {
+		names := make([]string, len(Layouts))
+		for i, l := range Layouts {
+			names[i] = l.Name
+		}
+		return nil, fmt.Errorf("formato de timestamp inválido %q: use %s ou um layout Go como \"2006-01-02 15:04:05\"", s, strings.Join(names, ", "))
+	}
+	return &Layout{
+		Name: s,
+		re:   regexp.MustCompile(pattern),
+		parse: func(v string, loc *time.Location, ref time.Time) (time.Time, bool) {
+			t, err := time.ParseInLocation(s, v, loc)
+			if err != nil {
+				return time.Time{}, false
+			}
+			if !hasYear {
+				t = InferYear(t, ref)
+			}
+			return t, true
+		},
+	}, nil
+}
+
+// layoutTokens maps the elements of Go layouts to what they match, longest
+// first so that "2006" is not read as "2" followed by "006".
+var layoutTokens = []struct{ token, re string }{
+	{"January", `[A-Z][a-z]+`}, {"Monday", `[A-Z][a-z]+`},
+	{"Z07:00", `(?:Z|[+-]\d{2}:\d{2})`}, {"-07:00", `[+-]\d{2}:\d{2}`},
+	{"Z0700", `(?:Z|[+-]\d{4})`}, {"-0700", `[+-]\d{4}`},
+	{"2006", `\d{4}`}, {"Jan", `[A-Z][a-z]{2}`}, {"Mon", `[A-Z][a-z]{2}`},
+	{"MST", `[A-Z]{3,5}`}, {"-07", `[+-]\d{2}`},
+	{".000000000", `\.\d{9}`}, {".000000", `\.\d{6}`}, {".000", `\.\d{3}`},
+	{",000000000", `,\d{9}`}, {",000000", `,\d{6}`}, {",000", `,\d{3}`},
+	{".999999999", `(?:\.\d+)?`}, {".999999", `(?:\.\d+)?`}, {".999", `(?:\.\d+)?`},
+	{"01", `\d{2}`}, {"02", `\d{2}`}, {"_2", `[ \d]\d`}, {"15", `\d{2}`},
+	{"03", `\d{2}`}, {"04", `\d{2}`}, {"05", `\d{2}`}, {"06", `\d{2}`},
+	{"PM", `[AP]M`}, {"pm", `[ap]m`},
+	{"1", `\d{1,2}`}, {"2", `\d{1,2}`}, {"3", `\d{1,2}`}, {"4", `\d{1,2}`}, {"5", `\d{1,2}`},
+}
+
+// layoutRegexp turns a Go layout into an expression finding times written
+// with it. It reports false when the layout has no date or time element.
+func layoutRegexp(layout string) (pattern string, hasYear, ok bool) {
+	var b strings.Builder
+	elements := 0
+	for i := 0; i < len(layout); {
+		matched := false
+		for _, t := range layoutTokens {
+			if strings.HasPrefix(layout[i:], t.token) {
+				b.WriteString(t.re)
+				i += len(t.token)
+				elements++
+				hasYear = hasYear || t.token == "2006" || t.token == "06"
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteString(regexp.QuoteMeta(layout[i : i+1]))
+			i++
+		}
+	}
+	return b.String(), hasYear, elements > 0
+}
+
+// InferYear sets the year of a time read without one, such as a syslog
+// timestamp. The year of ref is assumed unless that puts the time more than
+// a day after ref, as happens with December lines read in January; those
+// belong to the year before.
+func InferYear(t, ref time.Time) time.Time {
+	if ref.IsZero() {
+		ref = time.Now()
+	}
+	at := func(year int) time.Time {
+		return time.Date(year, t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	}
+	if d := at(ref.Year()); !d.After(ref.Add(24 * time.Hour)) {
+		return d
+	}
+	return at(ref.Year() - 1)
+}
+
+// Epoch converts a Unix time, guessing its unit (seconds, milliseconds,
+// microseconds or nanoseconds) from its magnitude.
+func Epoch(f float64) time.Time {
+	abs := math.Abs(f)
+	switch {
+	case abs >= 1e17:
+		return time.Unix(0, int64(f))
+	case abs >= 1e14:
+		return time.UnixMicro(int64(f))
+	case abs >= 1e11:
+		return time.UnixMilli(int64(f))
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+// DefaultSample is how many lines a Detector looks at before settling on a
+// layout.
+const DefaultSample = 20
+
+// searchLimit bounds how far into a line timestamps are looked for.
+const searchLimit = 128
+
+// Detector finds the timestamps of one source. While sampling the first
+// lines it tries every layout and counts which one matches most often; from
+// then on only that layout is used, which is faster and keeps a number in
+// the message from being taken for a time. A Detector must not be shared
+// between sources.
+type Detector struct {
+	// Layout, when set, is used for every line instead of detecting one.
+	Layout *Layout
+	// Location is the zone of times written without one; nil means local
+	// time.
+	Location *time.Location
+	// Sample overrides DefaultSample when positive.
+	Sample int
+
+	seen int
+	hits []int
+}
+
+// Detect returns the timestamp of a line. ref is when the line was read.
+func (d *Detector) Detect(line string, ref time.Time) (time.Time, bool) {
+	if len(line) > searchLimit {
+		line = line[:searchLimit]
+	}
+	loc := d.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	if d.Layout != nil {
+		return d.Layout.Parse(line, loc, ref)
+	}
+
+	sample := d.Sample
+	if sample <= 0 {
+		sample = DefaultSample
+	}
+	if d.hits == nil {
+		d.hits = make([]int, len(Layouts))
+	}
+
+	var found time.Time
+	ok := false
+	for i, l := range Layouts {
+		if t, matched := l.Parse(line, loc, ref); matched {
+			d.hits[i]++
+			if !ok {
+				found, ok = t, true
+			}
+		}
+	}
+
+	d.seen++
+	if d.seen >= sample {
+		best := 0
+		for i, n := range d.hits {
+			if n > d.hits[best] {
+				best = i
+			}
+		}
+		if d.hits[best] > 0 {
+			d.Layout = Layouts[best]
+		}
+	}
+	return found, ok
+}
+
+// Time returns the time of a line. A time found by a parser is read again
+// from its text when it may lack a zone, with the layout of d first and
+// then the known ones; a line without one has its time detected.
+func (d *Detector) Time(l logline.Line) (time.Time, bool) {
+	if l.TimeText != "" {
+		loc := d.Location
+		if loc == nil {
+			loc = time.Local
+		}
+		if d.Layout != nil {
+			if t, ok := d.Layout.Parse(l.TimeText, loc, l.Ingested); ok {
+				return t, true
+			}
+		}
+		if t, ok := AtStart(l.TimeText, loc, l.Ingested); ok {
+			return t, true
+		}
+	}
+	if !l.Time.IsZero() {
+		return l.Time, true
+	}
+	return d.Detect(l.Raw, l.Ingested)
+}
+
+// Stamp sets the time of every line received from in, using d; see
+// Detector.Time.
+func Stamp(ctx context.Context, in <-chan logline.Line, d *Detector) <-chan logline.Line {
+	out := make(chan logline.Line)
+
+	go func() {
+		defer close(out)
+		for l := range in {
+			if t, ok := d.Time(l); ok {
+				l.Time = t
+			}
+			select {
+			case out <- l:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,195 @@
+package timestamp
+
+import (
+	"context"
+	"logagg/internal/logline"
+	"testing"
+	"time"
+)
+
+func TestLayouts(t *testing.T) {
+	ref := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	sp := time.FixedZone("", -3*3600)
+
+	tests := []struct {
+		layout string
+		line   string
+		want   time.Time
+	}{
+		{"rfc3339", "2024-01-15T10:23:45.5Z GET /health", time.Date(2024, 1, 15, 10, 23, 45, 5e8, time.UTC)},
+		{"rfc3339", "ts=2024-01-15T10:23:45-03:00 msg=x", time.Date(2024, 1, 15, 10, 23, 45, 0, sp)},
+		{"datetime", "2024-01-15 10:23:45,123 ERROR boom", time.Date(2024, 1, 15, 10, 23, 45, 123e6, time.UTC)},
+		{"datetime", "2024-01-15 10:23:45 -0300 WARN", time.Date(2024, 1, 15, 10, 23, 45, 0, sp)},
+		{"slash", "2024/01/15 10:23:45 listening", time.Date(2024, 1, 15, 10, 23, 45, 0, time.UTC)},
+		{"apache", `10.0.0.1 - - [10/Oct/2023:13:55:36 -0300] "GET /"`, time.Date(2023, 10, 10, 13, 55, 36, 0, sp)},
+		{"syslog", "Mar  1 10:00:00 host sshd[1]: x", time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)},
+		{"syslog", "<34>Dec 31 23:59:59 host app: x", time.Date(2023, 12, 31, 23, 59, 59, 0, time.UTC)},
+		{"epoch", "1705314225 job done", time.Unix(1705314225, 0)},
+		{"epoch", "[1705314225.25] job done", time.Unix(1705314225, 25e7)},
+		{"epoch_ms", "1705314225123 job done", time.UnixMilli(1705314225123)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.layout+" "+tt.line, func(t *testing.T) {
+			l, err := ParseLayout(tt.layout)
+			if err != nil {
+				t.Fatalf("ParseLayout() unexpected error = %v", err)
+			}
+			got, ok := l.Parse(tt.line, time.UTC, ref)
+			if !ok {
+				t.Fatal("Parse() ok = false, want true")
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLayout_Custom(t *testing.T) {
+	ref := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	l, err := ParseLayout("02.01.2006 15:04:05")
+	if err != nil {
+		t.Fatalf("ParseLayout() unexpected error = %v", err)
+	}
+	got, ok := l.Parse("INFO 15.01.2024 10:23:45 started", time.UTC, ref)
+	if !ok || !got.Equal(time.Date(2024, 1, 15, 10, 23, 45, 0, time.UTC)) {
+		t.Errorf("Parse() = %v, %v", got, ok)
+	}
+
+	// Without a year the time the line was read supplies it.
+	l, err = ParseLayout("Jan _2 15:04:05.000")
+	if err != nil {
+		t.Fatalf("ParseLayout() unexpected error = %v", err)
+	}
+	got, ok = l.Parse("Feb  3 08:00:00.250 kernel: x", time.UTC, ref)
+	if !ok || !got.Equal(time.Date(2024, 2, 3, 8, 0, 0, 25e7, time.UTC)) {
+		t.Errorf("Parse() = %v, %v", got, ok)
+	}
+
+	if _, err := ParseLayout("no elements here"); err == nil {
+		t.Error("ParseLayout() without date elements error = nil, want error")
+	}
+}
+
+func TestInferYear(t *testing.T) {
+	ref := time.Date(2025, 1, 1, 0, 5, 0, 0, time.UTC)
+	dec := time.Date(0, 12, 31, 23, 59, 0, 0, time.UTC)
+	if got := InferYear(dec, ref); got.Year() != 2024 {
+		t.Errorf("InferYear(Dec 31) read on Jan 1st = %v, want 2024", got)
+	}
+	jan := time.Date(0, 1, 1, 0, 1, 0, 0, time.UTC)
+	if got := InferYear(jan, ref); got.Year() != 2025 {
+		t.Errorf("InferYear(Jan 1) = %v, want 2025", got)
+	}
+}
+
+func TestEpoch(t *testing.T) {
+	want := time.Date(2024, 1, 15, 10, 23, 45, 0, time.UTC)
+	for _, f := range []float64{1705314225, 1705314225e3, 1705314225e6, 1705314225e9} {
+		if got := Epoch(f); !got.Equal(want) {
+			t.Errorf("Epoch(%v) = %v, want %v", f, got, want)
+		}
+	}
+}
+
+func TestDetector_SettlesOnMostFrequentLayout(t *testing.T) {
+	d := &Detector{Location: time.UTC, Sample: 3}
+
+	// An access log whose request path holds something that looks like
+	// an ISO date; the apache layout matches every line.
+	lines := []string{
+		`10.0.0.1 - - [10/Oct/2023:13:55:36 +0000] "GET /a HTTP/1.1" 200 1`,
+		`10.0.0.1 - - [10/Oct/2023:13:55:37 +0000] "GET /reports/2020-01-01T00:00:00Z HTTP/1.1" 200 1`,
+		`10.0.0.1 - - [10/Oct/2023:13:55:38 +0000] "GET /c HTTP/1.1" 200 1`,
+		`10.0.0.1 - - [10/Oct/2023:13:55:39 +0000] "GET /reports/2020-01-01T00:00:00Z HTTP/1.1" 200 1`,
+	}
+	for _, l := range lines {
+		d.Detect(l, time.Time{})
+	}
+	if d.Layout == nil || d.Layout.Name != "apache" {
+		t.Fatalf("expected apache layout to be chosen, got %+v", d.Layout)
+	}
+
+	got, ok := d.Detect(lines[3], time.Time{})
+	if !ok || !got.Equal(time.Date(2023, 10, 10, 13, 55, 39, 0, time.UTC)) {
+		t.Errorf("Detect() = %v, %v, want the apache timestamp", got, ok)
+	}
+}
+
+func TestDetector_KeepsSamplingUntilATimestampIsSeen(t *testing.T) {
+	d := &Detector{Sample: 1}
+	if _, ok := d.Detect("=== banner ===", time.Time{}); ok {
+		t.Error("Detect() on a banner ok = true, want false")
+	}
+	if d.Layout != nil {
+		t.Fatalf("expected no layout after lines without timestamps, got %s", d.Layout.Name)
+	}
+	if _, ok := d.Detect("2024/01/15 10:23:45 up", time.Time{}); !ok || d.Layout == nil || d.Layout.Name != "slash" {
+		t.Errorf("expected slash layout after the first timestamp, got %+v", d.Layout)
+	}
+}
+
+func TestDetector_Location(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*3600)
+	d := &Detector{Location: tokyo}
+
+	got, _ := d.Detect("2024-01-15 10:23:45 zoneless", time.Time{})
+	if !got.Equal(time.Date(2024, 1, 15, 10, 23, 45, 0, tokyo)) {
+		t.Errorf("Detect() = %v, want the time in JST", got)
+	}
+	got, _ = d.Detect("2024-01-15T10:23:45Z zoned", time.Time{})
+	if !got.Equal(time.Date(2024, 1, 15, 10, 23, 45, 0, time.UTC)) {
+		t.Errorf("Detect() = %v, want the zone of the line to win", got)
+	}
+}
+
+func TestStamp_KeepsParsedTime(t *testing.T) {
+	parsed := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	in := make(chan logline.Line, 2)
+	in <- logline.Line{Raw: "2024-01-15T10:23:45Z a", Time: parsed}
+	in <- logline.Line{Raw: "2024-01-15T10:23:45Z b"}
+	close(in)
+
+	var got []logline.Line
+	for l := range Stamp(context.Background(), in, &Detector{}) {
+		got = append(got, l)
+	}
+	if !got[0].Time.Equal(parsed) {
+		t.Errorf("expected the parser's time to be kept, got %v", got[0].Time)
+	}
+	if got[1].Time.IsZero() {
+		t.Error("expected a time to be detected")
+	}
+}
+
+func TestStamp_RereadsParsedTimeWithoutZone(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*3600)
+	ingested := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	in := make(chan logline.Line, 3)
+	// As the syslog and JSON parsers leave them.
+	in <- logline.Line{Raw: "Jan 15 10:00:00 host app: hi", Time: time.Date(2024, 1, 15, 10, 0, 0, 0, time.Local), TimeText: "Jan 15 10:00:00", Ingested: ingested}
+	in <- logline.Line{Raw: `{"time":"15.01.2024 10:00:00"}`, Ingested: ingested}
+	in <- logline.Line{Raw: `{"time":"2024-01-15 10:00:00+02:00"}`, TimeText: "2024-01-15 10:00:00+02:00", Ingested: ingested}
+	close(in)
+
+	layout, err := ParseLayout("02.01.2006 15:04:05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []time.Time
+	for l := range Stamp(context.Background(), in, &Detector{Layout: layout, Location: tokyo}) {
+		got = append(got, l.Time)
+	}
+	want := []time.Time{
+		time.Date(2024, 1, 15, 10, 0, 0, 0, tokyo),
+		time.Date(2024, 1, 15, 10, 0, 0, 0, tokyo),
+		time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC),
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("line %d: Time = %v, want %v", i+1, got[i], want[i])
+		}
+	}
+}
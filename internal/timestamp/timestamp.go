@@ -0,0 +1,29 @@
+package timestamp
+
+import (
+	"regexp"
+	"time"
+)
+
+// lead is what may come before the timestamp a line starts with: spaces
+// and an opening bracket, as in "[2024-01-15 10:23:45] INFO".
+var lead = regexp.MustCompile(`^\s*[\[(]?$`)
+
+// AtStart reads the timestamp a line starts with, trying Layouts in order.
+// Times written without a zone are taken in loc, and ref supplies the year
+// to layouts without one.
+func AtStart(line string, loc *time.Location, ref time.Time) (time.Time, bool) {
+	if len(line) > searchLimit {
+		line = line[:searchLimit]
+	}
+	for _, l := range Layouts {
+		m := l.re.FindStringSubmatchIndex(line)
+		if m == nil || !lead.MatchString(line[:m[0]]) {
+			continue
+		}
+		if t, ok := l.parse(line[m[len(m)-2]:m[len(m)-1]], loc, ref); ok {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
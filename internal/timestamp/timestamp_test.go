@@ -0,0 +1,76 @@
+package timestamp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAtStart(t *testing.T) {
+	loc := time.FixedZone("BRT", -3*3600)
+
+	tests := []struct {
+		name string
+		line string
+		want time.Time
+		ok   bool
+	}{
+		{
+			name: "date and time",
+			line: "2024-01-15 10:23:45 INFO Starting application",
+			want: time.Date(2024, 1, 15, 10, 23, 45, 0, loc),
+			ok:   true,
+		},
+		{
+			name: "comma milliseconds",
+			line: "2024-01-15 10:23:45,123 ERROR boom",
+			want: time.Date(2024, 1, 15, 10, 23, 45, 123000000, loc),
+			ok:   true,
+		},
+		{
+			name: "rfc3339",
+			line: "2024-01-15T10:23:45.5Z GET /health",
+			want: time.Date(2024, 1, 15, 10, 23, 45, 500000000, time.UTC),
+			ok:   true,
+		},
+		{
+			name: "go log package",
+			line: "2024/01/15 10:23:45 listening on :8080",
+			want: time.Date(2024, 1, 15, 10, 23, 45, 0, loc),
+			ok:   true,
+		},
+		{
+			name: "bracketed",
+			line: "[2024-01-15 10:23:45] INFO started",
+			want: time.Date(2024, 1, 15, 10, 23, 45, 0, loc),
+			ok:   true,
+		},
+		{
+			name: "syslog",
+			line: "Jan 15 10:23:45 host app: started",
+			want: time.Date(2024, 1, 15, 10, 23, 45, 0, loc),
+			ok:   true,
+		},
+		{
+			name: "later in the line",
+			line: "retrying since 2024-01-15 10:23:45",
+			ok:   false,
+		},
+		{
+			name: "no timestamp",
+			line: "    at com.example.Main.run(Main.java:42)",
+			ok:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := AtStart(tt.line, loc, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+			if ok != tt.ok {
+				t.Fatalf("AtStart() ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && !got.Equal(tt.want) {
+				t.Errorf("AtStart() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}